@@ -0,0 +1,91 @@
+package benchmark
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	gnarkio "github.com/consensys/gnark/io"
+)
+
+// Artifact_Stats holds the serialized sizes of an artifact and how long it took to serialize and deserialize it
+// Artifacts that don't have a compressed form (witnesses and constraint system) have a compressed size of -1
+type Artifact_Stats struct {
+	Name                 string
+	Compressed_size      int64
+	Raw_size             int64
+	Compressed_ser_dur   time.Duration
+	Compressed_deser_dur time.Duration
+	Raw_ser_dur          time.Duration
+	Raw_deser_dur        time.Duration
+}
+
+// raw_writer wraps an object that can be written without point compression so that it can be used as an io.WriterTo
+type raw_writer struct {
+	obj gnarkio.WriterRawTo
+}
+
+func (w raw_writer) WriteTo(wr io.Writer) (int64, error) {
+	return w.obj.WriteRawTo(wr)
+}
+
+// measure_artifacts serializes and deserializes each artifact of a groth16 benchmark and keeps track of the sizes and durations
+func measure_artifacts(curve_id ecc.ID, ccs constraint.ConstraintSystem, pk groth16.ProvingKey, vk groth16.VerifyingKey,
+	proof groth16.Proof, full_witness witness.Witness, public_witness witness.Witness) ([]Artifact_Stats, error) {
+
+	new_witness := func() io.ReaderFrom {
+		w, _ := witness.New(curve_id.ScalarField())
+		return w
+	}
+	var artifacts []Artifact_Stats
+	for _, a := range []struct {
+		name       string
+		compressed io.WriterTo
+		raw        io.WriterTo
+		new_obj    func() io.ReaderFrom
+	}{
+		{"Proof", proof, raw_writer{proof}, func() io.ReaderFrom { return groth16.NewProof(curve_id) }},
+		{"Public witness", nil, public_witness, new_witness},
+		{"Full witness", nil, full_witness, new_witness},
+		{"Verifying key", vk, raw_writer{vk}, func() io.ReaderFrom { return groth16.NewVerifyingKey(curve_id) }},
+		{"Proving key", pk, raw_writer{pk}, func() io.ReaderFrom { return groth16.NewProvingKey(curve_id) }},
+		{"Constraint system", nil, ccs, func() io.ReaderFrom { return groth16.NewCS(curve_id) }},
+	} {
+		stats := Artifact_Stats{Name: a.name, Compressed_size: -1}
+		var err error
+		if a.compressed != nil {
+			stats.Compressed_size, stats.Compressed_ser_dur, stats.Compressed_deser_dur, err = measure_serialization(a.compressed, a.new_obj())
+			if err != nil {
+				return nil, err
+			}
+		}
+		stats.Raw_size, stats.Raw_ser_dur, stats.Raw_deser_dur, err = measure_serialization(a.raw, a.new_obj())
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, stats)
+	}
+	return artifacts, nil
+}
+
+// measure_serialization writes the object into a buffer and reads it back into dst
+func measure_serialization(src io.WriterTo, dst io.ReaderFrom) (int64, time.Duration, time.Duration, error) {
+	var buf bytes.Buffer
+	start := time.Now()
+	size, err := src.WriteTo(&buf)
+	ser_dur := time.Since(start)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	start = time.Now()
+	_, err = dst.ReadFrom(&buf)
+	deser_dur := time.Since(start)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return size, ser_dur, deser_dur, nil
+}
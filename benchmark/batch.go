@@ -0,0 +1,174 @@
+package benchmark
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// Batch_Output holds the result of the batch verification of the proofs of a benchmark
+type Batch_Output struct {
+	Name      string
+	Nb_proofs int
+	// Duration of the randomized check of all the proofs at once
+	Batch_time time.Duration
+	Valid      bool
+	// Duration of the search of the invalid proofs after a failed batch (0 if the batch is valid)
+	Fallback_time time.Duration
+	// Indexes of the runs whose proofs are invalid, including the runs where the proof generation failed
+	Bad_proofs []int
+	// Average duration of the individual verification of the same proofs with groth16.Verify
+	Individual_time time.Duration
+}
+
+// batch_verify verifies the proofs with one randomized multi-pairing check
+// The groth16 equation e(A, B) = e(α, β) · e(L, γ) · e(C, δ) of each proof i is raised to a random power r_i and
+// the equations are multiplied together: Π e(r_i·A_i, B_i) · e(Σ r_i·C_i, -δ) · e(Σ r_i·L_i, -γ) · e(-(Σ r_i)·α, β) = 1
+// is a multi-pairing of n+3 pairs instead of n multi-pairings of 4 pairs, and the L_i are folded in one MSM on the
+// public inputs. With commitments, the hashes of the commitments are public inputs, the commitments of proof i are
+// added to L_i and the Pedersen proofs of knowledge e(F_i, g) · e(PoK_i, g^(-1/σ)) = 1 of the folded commitments F_i
+// are raised to other random powers s_i and folded in 2 more pairs: e(Σ s_i·F_i, g) · e(Σ s_i·PoK_i, g^(-1/σ)).
+// An invalid proof makes the check fail except with negligible probability over the r_i and s_i. When the batch
+// fails, the invalid proofs are found by splitting the batch in halves until each failing batch holds one proof.
+func batch_verify(vk groth16.VerifyingKey, proofs []groth16.Proof, public_witnesses []witness.Witness) (Batch_Output, error) {
+	outp := Batch_Output{Nb_proofs: len(proofs)}
+	// The runs where the proof generation failed have no proof to verify
+	var indexes []int
+	for i := range proofs {
+		if proofs[i] == nil {
+			outp.Bad_proofs = append(outp.Bad_proofs, i)
+		} else {
+			indexes = append(indexes, i)
+		}
+	}
+
+	start := time.Now()
+	check, err := new_batch_check(vk, proofs, public_witnesses)
+	if err != nil {
+		return outp, err
+	}
+	outp.Valid, err = check(indexes)
+	outp.Batch_time = time.Since(start)
+	if err != nil {
+		return outp, err
+	}
+	if !outp.Valid && len(indexes) > 1 {
+		start = time.Now()
+		bad_left, err := find_bad_proofs(check, indexes[:len(indexes)/2])
+		if err != nil {
+			return outp, err
+		}
+		bad_right, err := find_bad_proofs(check, indexes[len(indexes)/2:])
+		if err != nil {
+			return outp, err
+		}
+		outp.Fallback_time = time.Since(start)
+		outp.Bad_proofs = append(outp.Bad_proofs, append(bad_left, bad_right...)...)
+	} else if !outp.Valid {
+		outp.Bad_proofs = append(outp.Bad_proofs, indexes...)
+	}
+	outp.Valid = outp.Valid && len(outp.Bad_proofs) == 0
+	return outp, nil
+}
+
+// find_bad_proofs returns the indexes of the invalid proofs of the batch by checking it and its halves recursively
+func find_bad_proofs(check func([]int) (bool, error), indexes []int) ([]int, error) {
+	if len(indexes) == 0 {
+		return nil, nil
+	}
+	valid, err := check(indexes)
+	if err != nil || valid {
+		return nil, err
+	}
+	if len(indexes) == 1 {
+		return indexes, nil
+	}
+	bad_left, err := find_bad_proofs(check, indexes[:len(indexes)/2])
+	if err != nil {
+		return nil, err
+	}
+	bad_right, err := find_bad_proofs(check, indexes[len(indexes)/2:])
+	if err != nil {
+		return nil, err
+	}
+	return append(bad_left, bad_right...), nil
+}
+
+// run_batch verifies the proofs of the runs in a batch and compares it to their individual verification
+// In the negative mode, the proof of the first run is also replaced by its tampered version in a second batch to
+// measure how long it takes to find it.
+func run_batch(outp *Benchmark_Output, vk groth16.VerifyingKey, proofs []groth16.Proof, public_witnesses []witness.Witness) error {
+	var individual_time time.Duration
+	for i := range outp.Start_proof_ver {
+		individual_time += outp.End_proof_ver[i].Sub(outp.Start_proof_ver[i])
+	}
+	individual_time /= time.Duration(len(outp.Start_proof_ver))
+
+	fmt.Printf("Batch verification of %d proofs...\n", len(proofs))
+	batch, err := batch_verify(vk, proofs, public_witnesses)
+	if err != nil {
+		return err
+	}
+	batch.Name = "Valid proofs"
+	batch.Individual_time = individual_time
+	print_batch(batch)
+	outp.Batch_outputs = append(outp.Batch_outputs, batch)
+
+	if outp.Negative && proofs[0] != nil {
+		tampered, err := tamper_proof(proofs[0])
+		if err != nil {
+			return err
+		}
+		tampered_proofs := append([]groth16.Proof{tampered}, proofs[1:]...)
+		fmt.Printf("Batch verification of %d proofs with a tampered proof...\n", len(proofs))
+		batch, err = batch_verify(vk, tampered_proofs, public_witnesses)
+		if err != nil {
+			return err
+		}
+		batch.Name = "Tampered first proof"
+		batch.Individual_time = individual_time
+		print_batch(batch)
+		outp.Batch_outputs = append(outp.Batch_outputs, batch)
+	}
+	return nil
+}
+
+func print_batch(batch Batch_Output) {
+	amortized := batch.Batch_time / time.Duration(batch.Nb_proofs)
+	fmt.Printf("Batch took %s (%s per proof, %s per proof individually)\n", batch.Batch_time.Round(time.Microsecond),
+		amortized.Round(time.Microsecond), batch.Individual_time.Round(time.Microsecond))
+	if batch.Valid {
+		fmt.Println("All the proofs are valid!")
+	} else {
+		fmt.Printf("Invalid proofs: %v (found in %s)\n", batch.Bad_proofs, batch.Fallback_time.Round(time.Microsecond))
+	}
+}
+
+// write_batch writes the batch verifications (durations in ms) in batch_verification.csv
+// The speed-up is the average individual verification divided by the amortized batch verification.
+func write_batch(outp_folderpath string, batches []Batch_Output) error {
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d.Microseconds())/1000.0, 'f', 3, 64)
+	}
+	var data_csv [][]string
+	data_csv = append(data_csv, []string{"Batch", "Proofs", "Batch verification", "Amortized verification", "Avg individual verification",
+		"Speed-up", "Valid", "Fallback", "Invalid proofs"})
+	for _, b := range batches {
+		amortized := b.Batch_time / time.Duration(b.Nb_proofs)
+		speed_up := ""
+		if amortized > 0 {
+			speed_up = strconv.FormatFloat(float64(b.Individual_time)/float64(amortized), 'f', 2, 64)
+		}
+		bad := make([]string, len(b.Bad_proofs))
+		for i, index := range b.Bad_proofs {
+			bad[i] = strconv.Itoa(index)
+		}
+		data_csv = append(data_csv, []string{b.Name, strconv.Itoa(b.Nb_proofs), ms(b.Batch_time), ms(amortized), ms(b.Individual_time),
+			speed_up, strconv.FormatBool(b.Valid), ms(b.Fallback_time), strings.Join(bad, " ")})
+	}
+	return write_CSV_file(fmt.Sprintf("%s/batch_verification.csv", outp_folderpath), data_csv)
+}
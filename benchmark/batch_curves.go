@@ -0,0 +1,459 @@
+package benchmark
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"hash"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377"
+	fr_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	hash_to_field_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/hash_to_field"
+	pedersen_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/pedersen"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	hash_to_field_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/hash_to_field"
+	pedersen_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/pedersen"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	hash_to_field_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/hash_to_field"
+	pedersen_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/pedersen"
+	bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761"
+	fr_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr"
+	hash_to_field_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/hash_to_field"
+	pedersen_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/pedersen"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bls12377 "github.com/consensys/gnark/backend/groth16/bls12-377"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	groth16_bw6761 "github.com/consensys/gnark/backend/groth16/bw6-761"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+)
+
+// new_batch_check returns a function checking the proofs of the given indexes with one randomized multi-pairing
+// The nil proofs must not be checked.
+func new_batch_check(vk groth16.VerifyingKey, proofs []groth16.Proof, public_witnesses []witness.Witness) (func([]int) (bool, error), error) {
+	switch vk := vk.(type) {
+	case *groth16_bn254.VerifyingKey:
+		return bn254_batch_check(vk, proofs, public_witnesses)
+	case *groth16_bls12377.VerifyingKey:
+		return bls12377_batch_check(vk, proofs, public_witnesses)
+	case *groth16_bls12381.VerifyingKey:
+		return bls12381_batch_check(vk, proofs, public_witnesses)
+	case *groth16_bw6761.VerifyingKey:
+		return bw6761_batch_check(vk, proofs, public_witnesses)
+	}
+	return nil, fmt.Errorf("curve %s is not supported", vk.CurveID().String())
+}
+
+// The keys and the proofs of gnark have no interface to their points, so each curve converts its key and its proofs
+// to batch_key and batch_proof, gives its operations in batch_ops and the check itself is written once in batch_check.
+
+// batch_key holds the points of a groth16 verifying key
+type batch_key[G1, G2 any] struct {
+	alpha                      G1
+	k                          []G1
+	beta, delta_neg, gamma_neg G2
+	// Key of the Pedersen commitments: e(F, g)·e(PoK, g^(-1/σ)) = 1 for the folded commitments F of a proof and their
+	// proof of knowledge PoK
+	pedersen_g, pedersen_g_root_sigma_neg G2
+	// Indexes of the public inputs committed by each commitment
+	committed [][]int
+}
+
+// batch_proof holds the points of a groth16 proof
+type batch_proof[G1, G2 any] struct {
+	ar, krs     G1
+	bs          G2
+	commitments []G1
+	pok         G1
+}
+
+// batch_ops holds the operations of a curve used by the batch verification
+type batch_ops[G1, G2 any] struct {
+	// Order of the groups and size of a scalar in bytes
+	order    *big.Int
+	fr_bytes int
+
+	g1_in_subgroup func(*G1) bool
+	g2_in_subgroup func(*G2) bool
+	g1_marshal     func(*G1) []byte
+	scalar_mul     func(*G1, *big.Int) G1
+	msm            func([]G1, []*big.Int) (G1, error)
+	// fold_commitments folds the commitments of a proof like pedersen.FoldCommitments
+	fold_commitments func([]G1, []byte) (G1, error)
+	// new_hash returns the hash to the scalar field of the commitments used by groth16.Verify
+	new_hash      func() hash.Hash
+	pairing_check func([]G1, []G2) (bool, error)
+}
+
+// batch_check returns the function checking the proofs of the given indexes, see batch_verify
+// Each proof is first checked like in groth16.Verify: its points must be in their subgroups and its numbers of public
+// inputs and of commitments must match the key. The hash of each commitment and of the public inputs it commits to is
+// appended to the public inputs of the proof, and the commitments of the proof are folded with these hashes.
+func batch_check[G1, G2 any](key batch_key[G1, G2], proofs []*batch_proof[G1, G2], public_inputs [][]*big.Int, ops batch_ops[G1, G2]) (func([]int) (bool, error), error) {
+	nb_commitments := len(key.committed)
+	valid := make([]bool, len(proofs))
+	folded := make([]G1, len(proofs))
+	for i, proof := range proofs {
+		if proof == nil {
+			continue
+		}
+		valid[i] = ops.g1_in_subgroup(&proof.ar) && ops.g1_in_subgroup(&proof.krs) && ops.g2_in_subgroup(&proof.bs) &&
+			len(public_inputs[i]) == len(key.k)-1-nb_commitments && len(proof.commitments) == nb_commitments
+		if !valid[i] || nb_commitments == 0 {
+			continue
+		}
+		valid[i] = ops.g1_in_subgroup(&proof.pok)
+		for c := range proof.commitments {
+			valid[i] = valid[i] && ops.g1_in_subgroup(&proof.commitments[c])
+		}
+		if !valid[i] {
+			continue
+		}
+		h := ops.new_hash()
+		public := public_inputs[i]
+		var seed []byte
+		for c, committed := range key.committed {
+			h.Reset()
+			h.Write(ops.g1_marshal(&proof.commitments[c]))
+			for _, j := range committed {
+				h.Write(public[j-1].FillBytes(make([]byte, ops.fr_bytes)))
+			}
+			res := new(big.Int).SetBytes(h.Sum(nil)[:min(ops.fr_bytes, h.Size())])
+			res.Mod(res, ops.order)
+			public_inputs[i] = append(public_inputs[i], res)
+			seed = append(seed, res.FillBytes(make([]byte, ops.fr_bytes))...)
+		}
+		var err error
+		if folded[i], err = ops.fold_commitments(proof.commitments, seed); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(indexes []int) (bool, error) {
+		n := len(indexes)
+		if n == 0 {
+			return true, nil
+		}
+		P := make([]G1, 0, n+5)
+		Q := make([]G2, 0, n+5)
+		r := make([]*big.Int, n)
+		krs := make([]G1, n)
+		// Σ r_i·L_i is one MSM on the points K_j followed by the commitments of the proofs: the scalar of the constant
+		// wire K_0 is Σ r_i, the one of the public input K_j is Σ r_i·x_ij and the one of each commitment of proof i is r_i
+		l_points := append(make([]G1, 0, len(key.k)+n*nb_commitments), key.k...)
+		l_scalars := make([]*big.Int, len(key.k), len(key.k)+n*nb_commitments)
+		for j := range l_scalars {
+			l_scalars[j] = new(big.Int)
+		}
+		// The proofs of knowledge are checked with other random powers s_i: Π e(s_i·F_i, g)·e(s_i·PoK_i, g^(-1/σ)) = 1
+		var s []*big.Int
+		var pedersen_f, pedersen_pok []G1
+		var tmp big.Int
+		for k, i := range indexes {
+			if !valid[i] {
+				return false, nil
+			}
+			proof := proofs[i]
+			var err error
+			if r[k], err = rand.Int(rand.Reader, ops.order); err != nil {
+				return false, err
+			}
+			l_scalars[0].Add(l_scalars[0], r[k])
+			for j, x := range public_inputs[i] {
+				l_scalars[j+1].Add(l_scalars[j+1], tmp.Mul(r[k], x))
+			}
+			for c := range proof.commitments {
+				l_points = append(l_points, proof.commitments[c])
+				l_scalars = append(l_scalars, r[k])
+			}
+			P = append(P, ops.scalar_mul(&proof.ar, r[k]))
+			Q = append(Q, proof.bs)
+			krs[k] = proof.krs
+			if nb_commitments > 0 {
+				s_k, err := rand.Int(rand.Reader, ops.order)
+				if err != nil {
+					return false, err
+				}
+				s = append(s, s_k)
+				pedersen_f = append(pedersen_f, folded[i])
+				pedersen_pok = append(pedersen_pok, proof.pok)
+			}
+		}
+		for j := range l_scalars {
+			l_scalars[j].Mod(l_scalars[j], ops.order)
+		}
+		c, err := ops.msm(krs, r)
+		if err != nil {
+			return false, err
+		}
+		l, err := ops.msm(l_points, l_scalars)
+		if err != nil {
+			return false, err
+		}
+		alpha := ops.scalar_mul(&key.alpha, new(big.Int).Sub(ops.order, l_scalars[0]))
+		P = append(P, c, l, alpha)
+		Q = append(Q, key.delta_neg, key.gamma_neg, key.beta)
+		if nb_commitments > 0 {
+			f, err := ops.msm(pedersen_f, s)
+			if err != nil {
+				return false, err
+			}
+			pok, err := ops.msm(pedersen_pok, s)
+			if err != nil {
+				return false, err
+			}
+			P = append(P, f, pok)
+			Q = append(Q, key.pedersen_g, key.pedersen_g_root_sigma_neg)
+		}
+		return ops.pairing_check(P, Q)
+	}, nil
+}
+
+// big_ints converts a vector of the scalar field of a curve to integers
+func big_ints[E any, PE interface {
+	*E
+	BigInt(*big.Int) *big.Int
+}](vector []E) []*big.Int {
+	res := make([]*big.Int, len(vector))
+	for i := range vector {
+		res[i] = PE(&vector[i]).BigInt(new(big.Int))
+	}
+	return res
+}
+
+// fr_elements converts integers to a vector of the scalar field of a curve
+func fr_elements[E any, PE interface {
+	*E
+	SetBigInt(*big.Int) *E
+}](scalars []*big.Int) []E {
+	res := make([]E, len(scalars))
+	for i := range scalars {
+		PE(&res[i]).SetBigInt(scalars[i])
+	}
+	return res
+}
+
+// --- bn254 ---
+
+func bn254_batch_check(vk *groth16_bn254.VerifyingKey, proofs []groth16.Proof, public_witnesses []witness.Witness) (func([]int) (bool, error), error) {
+	key := batch_key[bn254.G1Affine, bn254.G2Affine]{alpha: vk.G1.Alpha, k: vk.G1.K, beta: vk.G2.Beta,
+		committed: vk.PublicAndCommitmentCommitted}
+	key.delta_neg.Neg(&vk.G2.Delta)
+	key.gamma_neg.Neg(&vk.G2.Gamma)
+	if len(key.committed) > 0 {
+		// The points of the Pedersen key are not exported, they are read back from its serialization
+		var buf bytes.Buffer
+		if _, err := vk.CommitmentKey.WriteTo(&buf); err != nil {
+			return nil, err
+		}
+		dec := bn254.NewDecoder(&buf)
+		if err := dec.Decode(&key.pedersen_g); err != nil {
+			return nil, err
+		}
+		if err := dec.Decode(&key.pedersen_g_root_sigma_neg); err != nil {
+			return nil, err
+		}
+	}
+	batch_proofs := make([]*batch_proof[bn254.G1Affine, bn254.G2Affine], len(proofs))
+	public_inputs := make([][]*big.Int, len(proofs))
+	for i := range proofs {
+		if proofs[i] == nil {
+			continue
+		}
+		p := proofs[i].(*groth16_bn254.Proof)
+		batch_proofs[i] = &batch_proof[bn254.G1Affine, bn254.G2Affine]{ar: p.Ar, krs: p.Krs, bs: p.Bs,
+			commitments: p.Commitments, pok: p.CommitmentPok}
+		public_inputs[i] = big_ints(public_witnesses[i].Vector().(fr_bn254.Vector))
+	}
+	ops := batch_ops[bn254.G1Affine, bn254.G2Affine]{
+		order:          fr_bn254.Modulus(),
+		fr_bytes:       fr_bn254.Bytes,
+		g1_in_subgroup: (*bn254.G1Affine).IsInSubGroup,
+		g2_in_subgroup: (*bn254.G2Affine).IsInSubGroup,
+		g1_marshal:     (*bn254.G1Affine).Marshal,
+		scalar_mul: func(p *bn254.G1Affine, s *big.Int) (res bn254.G1Affine) {
+			res.ScalarMultiplication(p, s)
+			return
+		},
+		msm: func(points []bn254.G1Affine, scalars []*big.Int) (res bn254.G1Affine, err error) {
+			_, err = res.MultiExp(points, fr_elements[fr_bn254.Element](scalars), ecc.MultiExpConfig{})
+			return
+		},
+		fold_commitments: func(commitments []bn254.G1Affine, seed []byte) (bn254.G1Affine, error) {
+			return pedersen_bn254.FoldCommitments(commitments, seed)
+		},
+		new_hash:      func() hash.Hash { return hash_to_field_bn254.New([]byte(constraint.CommitmentDst)) },
+		pairing_check: bn254.PairingCheck,
+	}
+	return batch_check(key, batch_proofs, public_inputs, ops)
+}
+
+// --- bls12-377 ---
+
+func bls12377_batch_check(vk *groth16_bls12377.VerifyingKey, proofs []groth16.Proof, public_witnesses []witness.Witness) (func([]int) (bool, error), error) {
+	key := batch_key[bls12377.G1Affine, bls12377.G2Affine]{alpha: vk.G1.Alpha, k: vk.G1.K, beta: vk.G2.Beta,
+		committed: vk.PublicAndCommitmentCommitted}
+	key.delta_neg.Neg(&vk.G2.Delta)
+	key.gamma_neg.Neg(&vk.G2.Gamma)
+	if len(key.committed) > 0 {
+		// The points of the Pedersen key are not exported, they are read back from its serialization
+		var buf bytes.Buffer
+		if _, err := vk.CommitmentKey.WriteTo(&buf); err != nil {
+			return nil, err
+		}
+		dec := bls12377.NewDecoder(&buf)
+		if err := dec.Decode(&key.pedersen_g); err != nil {
+			return nil, err
+		}
+		if err := dec.Decode(&key.pedersen_g_root_sigma_neg); err != nil {
+			return nil, err
+		}
+	}
+	batch_proofs := make([]*batch_proof[bls12377.G1Affine, bls12377.G2Affine], len(proofs))
+	public_inputs := make([][]*big.Int, len(proofs))
+	for i := range proofs {
+		if proofs[i] == nil {
+			continue
+		}
+		p := proofs[i].(*groth16_bls12377.Proof)
+		batch_proofs[i] = &batch_proof[bls12377.G1Affine, bls12377.G2Affine]{ar: p.Ar, krs: p.Krs, bs: p.Bs,
+			commitments: p.Commitments, pok: p.CommitmentPok}
+		public_inputs[i] = big_ints(public_witnesses[i].Vector().(fr_bls12377.Vector))
+	}
+	ops := batch_ops[bls12377.G1Affine, bls12377.G2Affine]{
+		order:          fr_bls12377.Modulus(),
+		fr_bytes:       fr_bls12377.Bytes,
+		g1_in_subgroup: (*bls12377.G1Affine).IsInSubGroup,
+		g2_in_subgroup: (*bls12377.G2Affine).IsInSubGroup,
+		g1_marshal:     (*bls12377.G1Affine).Marshal,
+		scalar_mul: func(p *bls12377.G1Affine, s *big.Int) (res bls12377.G1Affine) {
+			res.ScalarMultiplication(p, s)
+			return
+		},
+		msm: func(points []bls12377.G1Affine, scalars []*big.Int) (res bls12377.G1Affine, err error) {
+			_, err = res.MultiExp(points, fr_elements[fr_bls12377.Element](scalars), ecc.MultiExpConfig{})
+			return
+		},
+		fold_commitments: func(commitments []bls12377.G1Affine, seed []byte) (bls12377.G1Affine, error) {
+			return pedersen_bls12377.FoldCommitments(commitments, seed)
+		},
+		new_hash:      func() hash.Hash { return hash_to_field_bls12377.New([]byte(constraint.CommitmentDst)) },
+		pairing_check: bls12377.PairingCheck,
+	}
+	return batch_check(key, batch_proofs, public_inputs, ops)
+}
+
+// --- bls12-381 ---
+
+func bls12381_batch_check(vk *groth16_bls12381.VerifyingKey, proofs []groth16.Proof, public_witnesses []witness.Witness) (func([]int) (bool, error), error) {
+	key := batch_key[bls12381.G1Affine, bls12381.G2Affine]{alpha: vk.G1.Alpha, k: vk.G1.K, beta: vk.G2.Beta,
+		committed: vk.PublicAndCommitmentCommitted}
+	key.delta_neg.Neg(&vk.G2.Delta)
+	key.gamma_neg.Neg(&vk.G2.Gamma)
+	if len(key.committed) > 0 {
+		// The points of the Pedersen key are not exported, they are read back from its serialization
+		var buf bytes.Buffer
+		if _, err := vk.CommitmentKey.WriteTo(&buf); err != nil {
+			return nil, err
+		}
+		dec := bls12381.NewDecoder(&buf)
+		if err := dec.Decode(&key.pedersen_g); err != nil {
+			return nil, err
+		}
+		if err := dec.Decode(&key.pedersen_g_root_sigma_neg); err != nil {
+			return nil, err
+		}
+	}
+	batch_proofs := make([]*batch_proof[bls12381.G1Affine, bls12381.G2Affine], len(proofs))
+	public_inputs := make([][]*big.Int, len(proofs))
+	for i := range proofs {
+		if proofs[i] == nil {
+			continue
+		}
+		p := proofs[i].(*groth16_bls12381.Proof)
+		batch_proofs[i] = &batch_proof[bls12381.G1Affine, bls12381.G2Affine]{ar: p.Ar, krs: p.Krs, bs: p.Bs,
+			commitments: p.Commitments, pok: p.CommitmentPok}
+		public_inputs[i] = big_ints(public_witnesses[i].Vector().(fr_bls12381.Vector))
+	}
+	ops := batch_ops[bls12381.G1Affine, bls12381.G2Affine]{
+		order:          fr_bls12381.Modulus(),
+		fr_bytes:       fr_bls12381.Bytes,
+		g1_in_subgroup: (*bls12381.G1Affine).IsInSubGroup,
+		g2_in_subgroup: (*bls12381.G2Affine).IsInSubGroup,
+		g1_marshal:     (*bls12381.G1Affine).Marshal,
+		scalar_mul: func(p *bls12381.G1Affine, s *big.Int) (res bls12381.G1Affine) {
+			res.ScalarMultiplication(p, s)
+			return
+		},
+		msm: func(points []bls12381.G1Affine, scalars []*big.Int) (res bls12381.G1Affine, err error) {
+			_, err = res.MultiExp(points, fr_elements[fr_bls12381.Element](scalars), ecc.MultiExpConfig{})
+			return
+		},
+		fold_commitments: func(commitments []bls12381.G1Affine, seed []byte) (bls12381.G1Affine, error) {
+			return pedersen_bls12381.FoldCommitments(commitments, seed)
+		},
+		new_hash:      func() hash.Hash { return hash_to_field_bls12381.New([]byte(constraint.CommitmentDst)) },
+		pairing_check: bls12381.PairingCheck,
+	}
+	return batch_check(key, batch_proofs, public_inputs, ops)
+}
+
+// --- bw6-761 ---
+
+func bw6761_batch_check(vk *groth16_bw6761.VerifyingKey, proofs []groth16.Proof, public_witnesses []witness.Witness) (func([]int) (bool, error), error) {
+	key := batch_key[bw6761.G1Affine, bw6761.G2Affine]{alpha: vk.G1.Alpha, k: vk.G1.K, beta: vk.G2.Beta,
+		committed: vk.PublicAndCommitmentCommitted}
+	key.delta_neg.Neg(&vk.G2.Delta)
+	key.gamma_neg.Neg(&vk.G2.Gamma)
+	if len(key.committed) > 0 {
+		// The points of the Pedersen key are not exported, they are read back from its serialization
+		var buf bytes.Buffer
+		if _, err := vk.CommitmentKey.WriteTo(&buf); err != nil {
+			return nil, err
+		}
+		dec := bw6761.NewDecoder(&buf)
+		if err := dec.Decode(&key.pedersen_g); err != nil {
+			return nil, err
+		}
+		if err := dec.Decode(&key.pedersen_g_root_sigma_neg); err != nil {
+			return nil, err
+		}
+	}
+	batch_proofs := make([]*batch_proof[bw6761.G1Affine, bw6761.G2Affine], len(proofs))
+	public_inputs := make([][]*big.Int, len(proofs))
+	for i := range proofs {
+		if proofs[i] == nil {
+			continue
+		}
+		p := proofs[i].(*groth16_bw6761.Proof)
+		batch_proofs[i] = &batch_proof[bw6761.G1Affine, bw6761.G2Affine]{ar: p.Ar, krs: p.Krs, bs: p.Bs,
+			commitments: p.Commitments, pok: p.CommitmentPok}
+		public_inputs[i] = big_ints(public_witnesses[i].Vector().(fr_bw6761.Vector))
+	}
+	ops := batch_ops[bw6761.G1Affine, bw6761.G2Affine]{
+		order:          fr_bw6761.Modulus(),
+		fr_bytes:       fr_bw6761.Bytes,
+		g1_in_subgroup: (*bw6761.G1Affine).IsInSubGroup,
+		g2_in_subgroup: (*bw6761.G2Affine).IsInSubGroup,
+		g1_marshal:     (*bw6761.G1Affine).Marshal,
+		scalar_mul: func(p *bw6761.G1Affine, s *big.Int) (res bw6761.G1Affine) {
+			res.ScalarMultiplication(p, s)
+			return
+		},
+		msm: func(points []bw6761.G1Affine, scalars []*big.Int) (res bw6761.G1Affine, err error) {
+			_, err = res.MultiExp(points, fr_elements[fr_bw6761.Element](scalars), ecc.MultiExpConfig{})
+			return
+		},
+		fold_commitments: func(commitments []bw6761.G1Affine, seed []byte) (bw6761.G1Affine, error) {
+			return pedersen_bw6761.FoldCommitments(commitments, seed)
+		},
+		new_hash:      func() hash.Hash { return hash_to_field_bw6761.New([]byte(constraint.CommitmentDst)) },
+		pairing_check: bw6761.PairingCheck,
+	}
+	return batch_check(key, batch_proofs, public_inputs, ops)
+}
@@ -0,0 +1,96 @@
+package benchmark
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/rangecheck"
+)
+
+// batch_test_circuit checks Y == X^3 + X + 5, the range check of X adds a commitment to the proofs
+type batch_test_circuit struct {
+	X           frontend.Variable
+	Y           frontend.Variable `gnark:",public"`
+	range_check bool
+}
+
+func (c *batch_test_circuit) Define(api frontend.API) error {
+	if c.range_check {
+		rangecheck.New(api).Check(c.X, 16)
+	}
+	api.AssertIsEqual(c.Y, api.Add(api.Mul(c.X, c.X, c.X), c.X, 5))
+	return nil
+}
+
+// The batch verification must find the same invalid proofs as groth16.Verify: a tampered proof, a proof checked
+// against the public inputs of another run and a run where the proof generation failed
+func TestBatch_verify_matches_verify(t *testing.T) {
+	for _, curve := range []ecc.ID{ecc.BN254, ecc.BLS12_377, ecc.BLS12_381, ecc.BW6_761} {
+		for _, range_check := range []bool{false, true} {
+			ccs, err := frontend.Compile(curve.ScalarField(), r1cs.NewBuilder, &batch_test_circuit{range_check: range_check})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if range_check != (Nb_commitments(ccs) > 0) {
+				t.Fatalf("%s: expected a commitment only with the range check", curve)
+			}
+			pk, vk, err := groth16.Setup(ccs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nb_runs := 5
+			proofs := make([]groth16.Proof, nb_runs)
+			public_witnesses := make([]witness.Witness, nb_runs)
+			for i := 0; i < nb_runs; i++ {
+				x := i + 2
+				full_witness, err := frontend.NewWitness(&batch_test_circuit{X: x, Y: x*x*x + x + 5}, curve.ScalarField())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if proofs[i], err = groth16.Prove(ccs, pk, full_witness); err != nil {
+					t.Fatal(err)
+				}
+				if public_witnesses[i], err = full_witness.Public(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			valid, err := batch_verify(vk, proofs, public_witnesses)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid.Valid || len(valid.Bad_proofs) != 0 {
+				t.Errorf("%s (commitment %v): the valid proofs are rejected, invalid proofs %v", curve, range_check, valid.Bad_proofs)
+			}
+
+			// Run 1 has a tampered proof, run 2 the public inputs of run 3 and the proof of run 4 was not generated
+			if proofs[1], err = tamper_proof(proofs[1]); err != nil {
+				t.Fatal(err)
+			}
+			public_witnesses[2] = public_witnesses[3]
+			proofs[4] = nil
+			var expected []int
+			for i := range proofs {
+				if proofs[i] == nil || groth16.Verify(proofs[i], vk, public_witnesses[i]) != nil {
+					expected = append(expected, i)
+				}
+			}
+			invalid, err := batch_verify(vk, proofs, public_witnesses)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The runs without a proof come first in the batch output
+			sort.Ints(invalid.Bad_proofs)
+			if invalid.Valid || !reflect.DeepEqual(invalid.Bad_proofs, expected) || !reflect.DeepEqual(expected, []int{1, 2, 4}) {
+				t.Errorf("%s (commitment %v): the batch finds the invalid proofs %v, groth16.Verify %v", curve, range_check,
+					invalid.Bad_proofs, expected)
+			}
+		}
+	}
+}
@@ -10,7 +10,10 @@ import (
 	"strconv"
 	"time"
 
-	"gnark_on_icicle/constants"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend/schema"
+
 	"gnark_on_icicle/gpu"
 )
 
@@ -41,31 +44,80 @@ type Benchmark_Output struct {
 	Start_proof_ver      []time.Time
 	End_proof_ver        []time.Time
 	Proof_valid          []bool
-	GPU_samples          []gpu.GPU_Sample
-	Dbg_log              string
+	// False for the runs where the proof generation failed, gnark logs no solver and prover durations for them
+	Proof_generated []bool
+	GPU_samples     []gpu.GPU_Sample
+	Dbg_log         string
+
+	// Negative tests
+	Start_invalid_proof_gen  []time.Time
+	End_invalid_proof_gen    []time.Time
+	Start_tampered_input_ver []time.Time
+	End_tampered_input_ver   []time.Time
+	Start_tampered_proof_ver []time.Time
+	End_tampered_proof_ver   []time.Time
+	Invalid_witness_rejected []bool
+	Tampered_input_rejected  []bool
+	Tampered_proof_rejected  []bool
+	// The tampered cases of a run are skipped when its valid proof could not be generated
+	Tampered_skipped []bool
+
+	// Serialized sizes of the artifacts of the first run
+	Artifacts []Artifact_Stats
+
+	// Artifacts saved in the output folder if Save_proofs is set
+	Vk               groth16.VerifyingKey
+	Proofs           []groth16.Proof
+	Public_witnesses []witness.Witness
+	Schema           *schema.Schema
+
+	// Results of the Solidity verifier if Solidity is set
+	Solidity_output Solidity_Output
+
+	// Statistics of the constraint system and constraint profile if Profile is set
+	Constraint_stats Constraint_Stats
+	Profile_path     string
+	Profile_top      string
+
+	// Duration and output size of each step of the MPC setup if MPC_contributions is set
+	MPC_steps []MPC_Step
+
+	// Results of the batch verification if Batch_verify is set
+	Batch_outputs []Batch_Output
 
 	Circuit        string
+	Backend        string
 	Curve          string
 	GPU_Acc        bool
 	GPU_Name       string
 	Num_runs       int
 	Nb_constraints int
+	Negative       bool
+	Save_proofs    bool
+	Solidity       bool
+	Profile        bool
+	Circuit_params map[string]int
+
+	MPC_contributions int
+	Batch_verify      bool
 }
 
 type benchmark_params struct {
-	Circuit              string `json:"Circuit"`
-	Curve                string `json:"Curve"`
-	Acc                  string `json:"Accelerator"`
-	GPU_name             string `json:"GPU name"`
-	Num_runs             int    `json:"Number of runs"`
-	Nb_constraints       int    `json:"Number of constraints"`
-	Cubic_x_size         int    `json:"Cubic X_SIZE"`
-	Exponentiate_x_size  int    `json:"Exponentiate X_SIZE"`
-	Exponentiate_e_size  int    `json:"Exponentiate E_BITSIZE"`
-	Sha256_preimage_size int    `json:"Sha256 preimage size"`
+	Circuit           string           `json:"Circuit"`
+	Backend           string           `json:"Backend"`
+	Curve             string           `json:"Curve"`
+	Acc               string           `json:"Accelerator"`
+	GPU_name          string           `json:"GPU name"`
+	Num_runs          int              `json:"Number of runs"`
+	Nb_constraints    int              `json:"Number of constraints"`
+	Circuit_params    map[string]int   `json:"Circuit parameters,omitempty"`
+	Constraint_system Constraint_Stats `json:"Constraint system"`
+	MPC_contributions int              `json:"MPC contributions,omitempty"`
+	Batch_verify      bool             `json:"Batch verification,omitempty"`
 }
 
-func Compile(outp Benchmark_Output) error {
+// create_output_folder creates the folder ./output/benchmark-i with the first index i that is not used yet
+func create_output_folder() (string, error) {
 	// Check if the output folder exists
 	_, err := os.Stat("./output")
 	if err != nil {
@@ -73,11 +125,11 @@ func Compile(outp Benchmark_Output) error {
 			// If the folder does not exist, create it
 			err := os.MkdirAll("./output", 0755)
 			if err != nil {
-				return err
+				return "", err
 			}
 		}
 	}
-	// Use the timestamp of the start fo the first arithmatization to name the folder where all the benchmark results are stored
+	// Use an incrementing index to name the folder where all the benchmark results are stored
 	var outp_folderpath string
 	i := 0
 	for {
@@ -87,12 +139,21 @@ func Compile(outp Benchmark_Output) error {
 			// If the folder does not exist, create it
 			err := os.MkdirAll(outp_folderpath, 0755)
 			if err != nil {
-				return err
+				return "", err
 			}
 			break
 		}
 		i++
 	}
+	return outp_folderpath, nil
+}
+
+// Compile writes the results of the benchmark in a new output folder and returns the path of the folder
+func Compile(outp Benchmark_Output) (string, error) {
+	outp_folderpath, err := create_output_folder()
+	if err != nil {
+		return "", err
+	}
 
 	// Parse the debug logs
 	var log_entries []Log_entry
@@ -116,21 +177,28 @@ func Compile(outp Benchmark_Output) error {
 			log_entries_proof_gen = append(log_entries_proof_gen, log_entries[i])
 		}
 	}
-	if len(log_entries_proof_gen) != outp.Num_runs && len(log_entries_sol_gen) != outp.Num_runs {
+	nb_generated := 0
+	for _, generated := range outp.Proof_generated {
+		if generated {
+			nb_generated++
+		}
+	}
+	if len(log_entries_proof_gen) != nb_generated && len(log_entries_sol_gen) != nb_generated {
 		err := errors.New("Some logs from gnark are missing")
-		return err
+		return "", err
 	}
 	// Create a JSON file to save the benchmark parameters
 	bench_params := benchmark_params{
-		Circuit:              outp.Circuit,
-		Curve:                outp.Curve,
-		Acc:                  map[bool]string{true: "GPU", false: "CPU"}[outp.GPU_Acc],
-		Num_runs:             outp.Num_runs,
-		Nb_constraints:       outp.Nb_constraints,
-		Cubic_x_size:         constants.X_SIZE_CUBIC,
-		Exponentiate_x_size:  constants.X_SIZE_EXP,
-		Exponentiate_e_size:  constants.E_BITSIZE,
-		Sha256_preimage_size: constants.PREIMAGE_SIZE,
+		Circuit:           outp.Circuit,
+		Backend:           outp.Backend,
+		Curve:             outp.Curve,
+		Acc:               map[bool]string{true: "GPU", false: "CPU"}[outp.GPU_Acc],
+		Num_runs:          outp.Num_runs,
+		Nb_constraints:    outp.Nb_constraints,
+		Circuit_params:    outp.Circuit_params,
+		Constraint_system: outp.Constraint_stats,
+		MPC_contributions: outp.MPC_contributions,
+		Batch_verify:      outp.Batch_verify,
 	}
 	if outp.GPU_Acc {
 		bench_params.GPU_name = outp.GPU_Name
@@ -139,7 +207,7 @@ func Compile(outp Benchmark_Output) error {
 	data_json, err := json.MarshalIndent(bench_params, "", "    ")
 	if err != nil {
 		fmt.Println("Error marshaling JSON:", err)
-		return err
+		return "", err
 	}
 	bench_params_filepath := fmt.Sprintf("%s/benchmark_parameters.json", outp_folderpath)
 
@@ -154,12 +222,18 @@ func Compile(outp Benchmark_Output) error {
 	copy(outp.End_proof_gen, outp.End_proof_gen_func)
 	// Create variables to keep track of the cumultative duration of each step to calculate the average later for the summary
 	var witness_gen_dur_cumul, sol_gen_dur_cumul, proof_gen_dur_cumul, proof_gen_func_dur_cumul, proof_ver_dur_cumul, full_run_dur_cumul int64
+	// Index of the logs of the next run whose proof was generated
+	log_index := 0
 	for i := 0; i < outp.Num_runs; i++ {
 		// The function groth16.prove performs both the solution generation and the proof generation there fore we need to extract
 		// timings from the gnark logs
+		// The durations of a run whose proof generation failed are left at 0
 		var sol_gen_dur, proof_gen_dur time.Duration
-		sol_gen_dur = time.Duration(int(log_entries_sol_gen[i].Duration)) * time.Millisecond
-		proof_gen_dur = time.Duration(int(log_entries_proof_gen[i].Duration)) * time.Millisecond
+		if outp.Proof_generated[i] {
+			sol_gen_dur = time.Duration(int(log_entries_sol_gen[log_index].Duration)) * time.Millisecond
+			proof_gen_dur = time.Duration(int(log_entries_proof_gen[log_index].Duration)) * time.Millisecond
+			log_index++
+		}
 		// Subtract the proof generation duration from the end time of the function to get the start time of the proof generation
 		// Subtract the solution generation duration from the start time of the proof generation to ge the start time of the solution generation
 		outp.Start_proof_gen = append(outp.Start_proof_gen, outp.End_proof_gen[i].Add(-proof_gen_dur))
@@ -282,9 +356,133 @@ func Compile(outp Benchmark_Output) error {
 		timestamps_filepath := fmt.Sprintf("%s/timestamps.csv", outp_folderpath)
 		write_CSV_file(timestamps_filepath, data_csv)
 	}
+	// Write the sizes of the artifacts and their serialization and deserialization durations
+	if len(outp.Artifacts) > 0 {
+		data_csv = data_csv[:0]
+		// Create the header
+		data_csv = append(data_csv, []string{"Artifact", "Compressed size", "Raw size", "Compressed serialization", "Compressed deserialization",
+			"Raw serialization", "Raw deserialization"})
+		for _, a := range outp.Artifacts {
+			// Artifacts without a compressed form only have raw values
+			compressed_size_str, compressed_ser_str, compressed_deser_str := "", "", ""
+			if a.Compressed_size >= 0 {
+				compressed_size_str = strconv.FormatInt(a.Compressed_size, 10)
+				compressed_ser_str = strconv.FormatFloat(float64(a.Compressed_ser_dur.Microseconds())/1000.0, 'f', 3, 64)
+				compressed_deser_str = strconv.FormatFloat(float64(a.Compressed_deser_dur.Microseconds())/1000.0, 'f', 3, 64)
+			}
+			raw_size_str := strconv.FormatInt(a.Raw_size, 10)
+			raw_ser_str := strconv.FormatFloat(float64(a.Raw_ser_dur.Microseconds())/1000.0, 'f', 3, 64)
+			raw_deser_str := strconv.FormatFloat(float64(a.Raw_deser_dur.Microseconds())/1000.0, 'f', 3, 64)
+			data_csv = append(data_csv, []string{a.Name, compressed_size_str, raw_size_str, compressed_ser_str, compressed_deser_str, raw_ser_str, raw_deser_str})
+		}
+		artifacts_filepath := fmt.Sprintf("%s/artifact_sizes.csv", outp_folderpath)
+		write_CSV_file(artifacts_filepath, data_csv)
+	}
+
+	// Write the constraint profile
+	if outp.Profile {
+		err = write_profile(outp_folderpath, outp)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Write the steps of the MPC setup
+	if len(outp.MPC_steps) > 0 {
+		err = write_mpc_steps(outp_folderpath, outp.MPC_steps)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Write the batch verifications
+	if len(outp.Batch_outputs) > 0 {
+		err = write_batch(outp_folderpath, outp.Batch_outputs)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Save the verifying key and the proof and public witness of each run
+	if outp.Save_proofs {
+		err = save_proofs(outp_folderpath, outp.Vk, outp.Proofs, outp.Public_witnesses, outp.Schema)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Save the Solidity verifier and the gas used to verify each proof
+	if outp.Solidity {
+		sol := outp.Solidity_output
+		err = os.WriteFile(fmt.Sprintf("%s/Verifier.sol", outp_folderpath), []byte(sol.Verifier), 0644)
+		if err != nil {
+			return "", err
+		}
+		data_csv = data_csv[:0]
+		// Create the header
+		data_csv = append(data_csv, []string{"Run number", "Execution gas", "Transaction gas", "EVM verification", "Proof verification", "Valid proof in EVM"})
+		var execution_gas_cumul, transaction_gas_cumul, nb_verified uint64
+		for i := 0; i < len(sol.Execution_gas); i++ {
+			if sol.Evm_called[i] {
+				execution_gas_cumul += sol.Execution_gas[i]
+				transaction_gas_cumul += sol.Transaction_gas[i]
+				nb_verified++
+			}
+			run_num_str := strconv.FormatInt(int64(i), 10)
+			evm_ver_str := strconv.FormatFloat(float64(sol.End_evm_ver[i].Sub(sol.Start_evm_ver[i]).Microseconds())/1000.0, 'f', 3, 64)
+			proof_ver_str := strconv.FormatFloat(float64(outp.End_proof_ver[i].Sub(outp.Start_proof_ver[i]).Microseconds())/1000.0, 'f', 3, 64)
+			data_csv = append(data_csv, []string{run_num_str, strconv.FormatUint(sol.Execution_gas[i], 10), strconv.FormatUint(sol.Transaction_gas[i], 10),
+				evm_ver_str, proof_ver_str, strconv.FormatBool(sol.Evm_proof_valid[i])})
+		}
+		write_CSV_file(fmt.Sprintf("%s/solidity_gas.csv", outp_folderpath), data_csv)
+		// Write the summary with the average gas of the proofs that were verified in the EVM
+		data_csv = data_csv[:0]
+		data_csv = append(data_csv, []string{"Bytecode size", "Deployment gas", "Avg execution gas", "Avg transaction gas"})
+		if nb_verified > 0 {
+			data_csv = append(data_csv, []string{strconv.Itoa(sol.Bytecode_size), strconv.FormatUint(sol.Deployment_gas, 10),
+				strconv.FormatUint(execution_gas_cumul/nb_verified, 10), strconv.FormatUint(transaction_gas_cumul/nb_verified, 10)})
+		}
+		write_CSV_file(fmt.Sprintf("%s/solidity_summary.csv", outp_folderpath), data_csv)
+	}
+
+	// Write the results of the negative tests and check that every negative case was rejected
+	var nb_accepted int
+	if outp.Negative {
+		data_csv = data_csv[:0]
+		// Create the header
+		data_csv = append(data_csv, []string{"Run number", "Invalid witness rejection", "Invalid witness rejected", "Tampered public input verification",
+			"Tampered public input rejected", "Tampered proof verification", "Tampered proof rejected"})
+		for i := 0; i < len(outp.Invalid_witness_rejected); i++ {
+			run_num_str := strconv.FormatInt(int64(i), 10)
+			invalid_proof_gen_str := strconv.FormatFloat(float64(outp.End_invalid_proof_gen[i].Sub(outp.Start_invalid_proof_gen[i]).Microseconds())/1000.0, 'f', 3, 64)
+			if !outp.Invalid_witness_rejected[i] {
+				nb_accepted++
+			}
+			// The skipped tampered cases are written as N/A and are not checked
+			if outp.Tampered_skipped[i] {
+				data_csv = append(data_csv, []string{run_num_str, invalid_proof_gen_str, strconv.FormatBool(outp.Invalid_witness_rejected[i]),
+					"N/A", "N/A", "N/A", "N/A"})
+				continue
+			}
+			tampered_input_ver_str := strconv.FormatFloat(float64(outp.End_tampered_input_ver[i].Sub(outp.Start_tampered_input_ver[i]).Microseconds())/1000.0, 'f', 3, 64)
+			tampered_proof_ver_str := strconv.FormatFloat(float64(outp.End_tampered_proof_ver[i].Sub(outp.Start_tampered_proof_ver[i]).Microseconds())/1000.0, 'f', 3, 64)
+			data_csv = append(data_csv, []string{run_num_str, invalid_proof_gen_str, strconv.FormatBool(outp.Invalid_witness_rejected[i]),
+				tampered_input_ver_str, strconv.FormatBool(outp.Tampered_input_rejected[i]), tampered_proof_ver_str, strconv.FormatBool(outp.Tampered_proof_rejected[i])})
+			for _, rejected := range []bool{outp.Tampered_input_rejected[i], outp.Tampered_proof_rejected[i]} {
+				if !rejected {
+					nb_accepted++
+				}
+			}
+		}
+		negative_res_filepath := fmt.Sprintf("%s/negative_results.csv", outp_folderpath)
+		write_CSV_file(negative_res_filepath, data_csv)
+	}
 	fmt.Println("Benchmark results written in", outp_folderpath)
 
-	return nil
+	if nb_accepted > 0 {
+		return outp_folderpath, fmt.Errorf("soundness check failed: %d negative cases were accepted", nb_accepted)
+	}
+	return outp_folderpath, nil
 }
 
 func GPU_samples_slice(GPU_samples []gpu.GPU_Sample, start time.Time, end time.Time) ([]time.Time, []uint64, []uint64, []uint64) {
@@ -379,9 +577,9 @@ func write_JSON_file(filepath string, data []byte) error {
 	file, err := os.Create(filepath)
 	if err != nil {
 		fmt.Println("Error creating file:", err)
-		file.Close()
 		return err
 	}
+	defer file.Close()
 
 	// Write the JSON data to the file
 	_, err = file.Write(data)
@@ -0,0 +1,258 @@
+package benchmark
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	cs_bls12377 "github.com/consensys/gnark/constraint/bls12-377"
+	cs_bls12381 "github.com/consensys/gnark/constraint/bls12-381"
+	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
+	cs_bw6761 "github.com/consensys/gnark/constraint/bw6-761"
+	"github.com/consensys/gnark/test/unsafekzg"
+)
+
+// Load_ccs reads a constraint system serialized with WriteTo and returns it with its type (R1CS or SCS)
+func Load_ccs(curve_id ecc.ID, ccs_path string) (constraint.ConstraintSystem, constraint.SystemType, error) {
+	// R1CS and SCS have the same concrete type for a given curve, the type of the system is stored in the file
+	ccs := groth16.NewCS(curve_id)
+	err := read_binary_file(ccs_path, ccs)
+	if err != nil {
+		return nil, constraint.SystemUnknown, fmt.Errorf("error reading constraint system: %v", err)
+	}
+	if ccs.Field().Cmp(curve_id.ScalarField()) != 0 {
+		return nil, constraint.SystemUnknown, fmt.Errorf("the constraint system was not compiled for %s", curve_id.String())
+	}
+	system_type := get_system_type(ccs)
+	if system_type != constraint.SystemR1CS && system_type != constraint.SystemSparseR1CS {
+		return nil, constraint.SystemUnknown, fmt.Errorf("unknown type of constraint system")
+	}
+	return ccs, system_type, nil
+}
+
+func get_system_type(ccs constraint.ConstraintSystem) constraint.SystemType {
+	switch c := ccs.(type) {
+	case *cs_bn254.R1CS:
+		return c.Type
+	case *cs_bls12377.R1CS:
+		return c.Type
+	case *cs_bls12381.R1CS:
+		return c.Type
+	case *cs_bw6761.R1CS:
+		return c.Type
+	}
+	return constraint.SystemUnknown
+}
+
+// Load_witness reads a full witness in the gnark binary format
+func Load_witness(curve_id ecc.ID, witness_path string) (witness.Witness, error) {
+	w, err := witness.New(curve_id.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	err = read_binary_file(witness_path, w)
+	if err != nil {
+		return nil, fmt.Errorf("error reading witness %s: %v", witness_path, err)
+	}
+	return w, nil
+}
+
+// Run_ccs benchmarks a constraint system loaded from a file instead of a circuit compiled in this binary.
+// Each witness file gives one run. An R1CS is proven with groth16 and an SCS with PLONK.
+func Run_ccs(cfg Config, ccs_path string, witness_paths []string) error {
+	load_ccs := func() (constraint.ConstraintSystem, error) {
+		ccs, _, err := Load_ccs(cfg.Curve_id, ccs_path)
+		return ccs, err
+	}
+	load_witness := func(i int) (witness.Witness, error) {
+		return Load_witness(cfg.Curve_id, witness_paths[i])
+	}
+	return Run_external(cfg, filepath.Base(ccs_path), load_ccs, len(witness_paths), load_witness)
+}
+
+// Run_external benchmarks a constraint system that is not compiled from a circuit of this binary (e.g. read from
+// a file or converted from another format). load_ccs returns the constraint system and load_witness(i) the full
+// witness of run i. The loading of the constraint system replaces the arithmetization and the loading of each
+// witness replaces the witness generation. The negative tests need the assignments of the circuit so they are
+// not supported.
+func Run_external(cfg Config, circuit_name string, load_ccs func() (constraint.ConstraintSystem, error), nb_runs int,
+	load_witness func(i int) (witness.Witness, error)) error {
+	if nb_runs <= 0 {
+		return fmt.Errorf("at least one witness is needed")
+	}
+	if cfg.Negative {
+		return fmt.Errorf("the negative tests are not supported for a loaded constraint system")
+	}
+
+	buf := capture_logs()
+
+	// Load the constraint system and keep track of how long it takes
+	start_load := time.Now()
+	ccs, err := load_ccs()
+	if err != nil {
+		return err
+	}
+	end_load := time.Now()
+	system_type := get_system_type(ccs)
+	use_plonk := system_type == constraint.SystemSparseR1CS
+	if use_plonk {
+		if cfg.Save_proofs || cfg.Solidity {
+			return fmt.Errorf("saving the proofs and the Solidity verifier are only supported for groth16 (R1CS)")
+		}
+		if cfg.GPU_Acc {
+			fmt.Println("The PLONK prover of gnark has no GPU acceleration, the proofs are generated on the CPU")
+		}
+	}
+	if use_plonk && cfg.MPC_contributions > 0 {
+		return fmt.Errorf("the MPC setup is only supported for groth16 (R1CS)")
+	}
+	if err := check_mpc(cfg); err != nil {
+		return err
+	}
+	if use_plonk && cfg.Batch_verify {
+		return fmt.Errorf("the batch verification is only supported for groth16 (R1CS)")
+	}
+	if err := Check_support(cfg, ccs); err != nil {
+		return err
+	}
+
+	// The profile needs the source of the circuit
+	if cfg.Profile {
+		fmt.Println("The constraint profile is not available for a loaded constraint system")
+		cfg.Profile = false
+	}
+	backend_name := "groth16"
+	if use_plonk {
+		backend_name = "plonk"
+	}
+	outp := new_output(cfg, circuit_name, backend_name, nb_runs)
+	outp.Start_arith = start_load
+	outp.End_arith = end_load
+	outp.Nb_constraints = ccs.GetNbConstraints()
+	outp.Constraint_stats = Get_constraint_stats(ccs)
+
+	// The sampling is also stopped when the benchmark returns early with an error
+	stop_sampling := start_GPU_sampling(cfg, &outp)
+	defer stop_sampling()
+
+	// Setup
+	// PLONK needs a KZG SRS, it is generated from a known secret and its generation is part of the setup
+	// The MPC setup of groth16 is measured as the setup
+	fmt.Println("Running setup...")
+	var groth16_pk groth16.ProvingKey
+	var groth16_vk groth16.VerifyingKey
+	var plonk_pk plonk.ProvingKey
+	var plonk_vk plonk.VerifyingKey
+	outp.Start_setup = time.Now()
+	if use_plonk {
+		srs, srs_lagrange, err := unsafekzg.NewSRS(ccs)
+		if err != nil {
+			return err
+		}
+		plonk_pk, plonk_vk, err = plonk.Setup(ccs, srs, srs_lagrange)
+		if err != nil {
+			return err
+		}
+	} else if cfg.MPC_contributions > 0 {
+		groth16_pk, groth16_vk, outp.MPC_steps, err = mpc_setup(ccs, cfg.MPC_contributions)
+		if err != nil {
+			return err
+		}
+	} else {
+		groth16_pk, groth16_vk, err = groth16.Setup(ccs)
+		if err != nil {
+			return err
+		}
+	}
+	outp.End_setup = time.Now()
+
+	// Keep the groth16 proofs and the public witnesses for the artifacts, the Solidity verifier and the saved proofs
+	proofs := make([]groth16.Proof, nb_runs)
+	public_witnesses := make([]witness.Witness, nb_runs)
+	var first_witness witness.Witness
+	for i := 0; i < nb_runs; i++ {
+		fmt.Printf("Benchmark run %d/%d\n", i+1, nb_runs)
+		// Witness loading
+		outp.Start_witness_gen = append(outp.Start_witness_gen, time.Now())
+		full_witness, err := load_witness(i)
+		if err != nil {
+			return err
+		}
+		public_witness, err := full_witness.Public()
+		if err != nil {
+			return err
+		}
+		outp.End_witness_gen = append(outp.End_witness_gen, time.Now())
+		// Prove & Verify
+		var plonk_proof plonk.Proof
+		outp.Start_proof_gen_func = append(outp.Start_proof_gen_func, time.Now())
+		if use_plonk {
+			plonk_proof, err = plonk.Prove(ccs, plonk_pk, full_witness)
+		} else {
+			proofs[i], err = prove(ccs, groth16_pk, full_witness, cfg.GPU_Acc)
+		}
+		outp.End_proof_gen_func = append(outp.End_proof_gen_func, time.Now())
+		if err != nil {
+			fmt.Println(err)
+			return fmt.Errorf("the proof generation of run %d failed, the witness might not match the constraint system", i)
+		}
+		outp.Proof_generated = append(outp.Proof_generated, true)
+		outp.Start_proof_ver = append(outp.Start_proof_ver, time.Now())
+		if use_plonk {
+			err = plonk.Verify(plonk_proof, plonk_vk, public_witness)
+		} else {
+			err = groth16.Verify(proofs[i], groth16_vk, public_witness)
+		}
+		outp.End_proof_ver = append(outp.End_proof_ver, time.Now())
+		if err == nil {
+			fmt.Println("Proof is valid!")
+		} else {
+			fmt.Println("Proof is invalid: ", err)
+		}
+		outp.Proof_valid = append(outp.Proof_valid, err == nil)
+		public_witnesses[i] = public_witness
+		if i == 0 {
+			first_witness = full_witness
+		}
+	}
+
+	outp.GPU_samples = stop_sampling()
+	if !use_plonk {
+		// Verify the proofs of all the runs at once
+		if cfg.Batch_verify {
+			err = run_batch(&outp, groth16_vk, proofs, public_witnesses)
+			if err != nil {
+				return err
+			}
+		}
+		// Measure the size of the artifacts of the first run
+		fmt.Println("Measuring artifact sizes...")
+		outp.Artifacts, err = measure_artifacts(cfg.Curve_id, ccs, groth16_pk, groth16_vk, proofs[0], first_witness, public_witnesses[0])
+		if err != nil {
+			return err
+		}
+		// Verify the proofs with the Solidity verifier
+		if cfg.Solidity {
+			fmt.Println("Verifying proofs in the EVM...")
+			outp.Solidity_output, err = run_solidity(groth16_vk, proofs, public_witnesses)
+			if err != nil {
+				return err
+			}
+		}
+		// The schema of the circuit is unknown so the public witnesses are only saved in the binary format
+		if cfg.Save_proofs {
+			outp.Vk = groth16_vk
+			outp.Proofs = proofs
+			outp.Public_witnesses = public_witnesses
+		}
+	}
+	outp.Dbg_log = buf.String()
+	fmt.Println("Compiling benchmark results...")
+	_, err = Compile(outp)
+	return err
+}
@@ -0,0 +1,130 @@
+package benchmark
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+)
+
+// Frontend_Stats holds the statistics of the compilation of a circuit with one frontend
+type Frontend_Stats struct {
+	Constraint_stats Constraint_Stats
+	Compile_time     time.Duration
+	// Bytes allocated during the compilation
+	Allocated uint64
+	// Bytes of the heap still used by the constraint system after the compilation
+	Retained uint64
+	Err      error
+}
+
+// Frontend_Comparison holds the statistics of the compilation of a circuit with the R1CS frontend (groth16) and
+// the SCS frontend (PLONK) on one curve
+type Frontend_Comparison struct {
+	Circuit string
+	Curve   ecc.ID
+	R1CS    Frontend_Stats
+	SCS     Frontend_Stats
+}
+
+// compile_frontend compiles the circuit with the builder and measures the time and the memory it takes
+// The garbage collector runs before the compilation so that the heap only holds the constraint system after it.
+func compile_frontend(curve_id ecc.ID, builder frontend.NewBuilder, circuit frontend.Circuit) Frontend_Stats {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	ccs, err := frontend.Compile(curve_id.ScalarField(), builder, circuit)
+	compile_time := time.Since(start)
+	if err != nil {
+		return Frontend_Stats{Err: err}
+	}
+	runtime.ReadMemStats(&after)
+	stats := Frontend_Stats{
+		Constraint_stats: Get_constraint_stats(ccs),
+		Compile_time:     compile_time,
+		Allocated:        after.TotalAlloc - before.TotalAlloc,
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	if after.HeapAlloc > before.HeapAlloc {
+		stats.Retained = after.HeapAlloc - before.HeapAlloc
+	}
+	runtime.KeepAlive(ccs)
+	return stats
+}
+
+// Compare_frontends compiles a circuit with r1cs.NewBuilder and with scs.NewBuilder without running the setup
+// new_circuit returns a new circuit for each compilation because the compilation sets the variables of the circuit.
+func Compare_frontends(curve_id ecc.ID, circuit_name string, new_circuit func() frontend.Circuit) Frontend_Comparison {
+	fmt.Printf("Compiling %s on %s with the R1CS frontend...\n", circuit_name, curve_id.String())
+	r1cs_stats := compile_frontend(curve_id, r1cs.NewBuilder, new_circuit())
+	fmt.Printf("Compiling %s on %s with the SCS frontend...\n", circuit_name, curve_id.String())
+	scs_stats := compile_frontend(curve_id, scs.NewBuilder, new_circuit())
+	return Frontend_Comparison{Circuit: circuit_name, Curve: curve_id, R1CS: r1cs_stats, SCS: scs_stats}
+}
+
+// Write_frontend_comparison prints the comparisons side by side and writes them in frontend_comparison.csv in a
+// new output folder. The ratio is the number of SCS constraints per R1CS constraint.
+func Write_frontend_comparison(comparisons []Frontend_Comparison) error {
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d.Microseconds())/1000.0, 'f', 3, 64)
+	}
+	mb := func(b uint64) string {
+		return strconv.FormatFloat(float64(b)/(1<<20), 'f', 3, 64)
+	}
+	error_message := func(err error) string {
+		if err == nil {
+			return ""
+		}
+		return err.Error()
+	}
+
+	fmt.Println("Comparison of the R1CS and SCS frontends:")
+	fmt.Printf("%-22s %-10s %14s %14s %7s %12s %12s %12s %12s\n", "Circuit", "Curve", "R1CS constr.", "SCS constr.", "Ratio",
+		"R1CS time", "SCS time", "R1CS alloc", "SCS alloc")
+	var data_csv [][]string
+	data_csv = append(data_csv, []string{"Circuit", "Curve",
+		"R1CS constraints", "R1CS internal variables", "R1CS commitments", "R1CS compile time (ms)", "R1CS allocated (MB)", "R1CS retained (MB)",
+		"SCS constraints", "SCS internal variables", "SCS commitments", "SCS compile time (ms)", "SCS allocated (MB)", "SCS retained (MB)",
+		"SCS/R1CS constraints", "R1CS error", "SCS error"})
+	for _, c := range comparisons {
+		r, s := c.R1CS, c.SCS
+		ratio := ""
+		if r.Err == nil && s.Err == nil && r.Constraint_stats.Nb_constraints > 0 {
+			ratio = strconv.FormatFloat(float64(s.Constraint_stats.Nb_constraints)/float64(r.Constraint_stats.Nb_constraints), 'f', 2, 64)
+		}
+		data_csv = append(data_csv, []string{c.Circuit, c.Curve.String(),
+			strconv.Itoa(r.Constraint_stats.Nb_constraints), strconv.Itoa(r.Constraint_stats.Nb_internal_variables),
+			strconv.Itoa(r.Constraint_stats.Nb_commitments), ms(r.Compile_time), mb(r.Allocated), mb(r.Retained),
+			strconv.Itoa(s.Constraint_stats.Nb_constraints), strconv.Itoa(s.Constraint_stats.Nb_internal_variables),
+			strconv.Itoa(s.Constraint_stats.Nb_commitments), ms(s.Compile_time), mb(s.Allocated), mb(s.Retained),
+			ratio, error_message(r.Err), error_message(s.Err)})
+
+		// The failed compilations are printed after the line of the circuit
+		fmt.Printf("%-22s %-10s %14d %14d %7s %12s %12s %10sMB %10sMB\n", c.Circuit, c.Curve.String(),
+			r.Constraint_stats.Nb_constraints, s.Constraint_stats.Nb_constraints, ratio,
+			r.Compile_time.Round(time.Millisecond), s.Compile_time.Round(time.Millisecond), mb(r.Allocated), mb(s.Allocated))
+		if r.Err != nil {
+			fmt.Println("\tR1CS error:", r.Err)
+		}
+		if s.Err != nil {
+			fmt.Println("\tSCS error:", s.Err)
+		}
+	}
+
+	outp_folderpath, err := create_output_folder()
+	if err != nil {
+		return err
+	}
+	if err := write_CSV_file(fmt.Sprintf("%s/frontend_comparison.csv", outp_folderpath), data_csv); err != nil {
+		return err
+	}
+	fmt.Println("Comparison written in", outp_folderpath)
+	return nil
+}
@@ -0,0 +1,204 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"time"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+
+	"gnark_on_icicle/gpu"
+)
+
+// Number of pairs of the multi-pairing of a groth16 verification: e(A, B) = e(α, β) · e(L, γ) · e(C, δ)
+const GROTH16_PAIRS = 4
+
+// Micro_Sizes holds the sizes of the primitives of the groth16 prover and verifier for a constraint system
+type Micro_Sizes struct {
+	Nb_constraints int `json:"Constraints"`
+	// Number of wires, which is the size of the MSMs on the wires of the prover (G1 and G2)
+	Msm_size int `json:"MSM size"`
+	// Size of the FFT domain of the prover, the smallest power of 2 that fits the constraints
+	Fft_size int `json:"FFT size"`
+	Nb_pairs int `json:"Multi-pairing pairs"`
+}
+
+// Micro_Result holds the duration of each repetition of a primitive
+type Micro_Result struct {
+	Name      string
+	Size      int
+	GPU_Acc   bool
+	Durations []time.Duration
+}
+
+// primitive is an operation of gnark-crypto (or of icicle) that is micro-benchmarked
+// prepare generates the random inputs and returns the operation to time and a function releasing the inputs (nil
+// if there is nothing to release), the generation of the inputs is not timed.
+type primitive struct {
+	name    string
+	size    int
+	gpu     bool
+	prepare func() (func() error, func(), error)
+}
+
+type micro_params struct {
+	Circuit  string           `json:"Circuit"`
+	Curve    string           `json:"Curve"`
+	Acc      string           `json:"Accelerator"`
+	GPU_name string           `json:"GPU name"`
+	Num_reps int              `json:"Number of repetitions"`
+	Sizes    Micro_Sizes      `json:"Sizes"`
+	Stats    Constraint_Stats `json:"Constraint system"`
+}
+
+// Get_micro_sizes returns the sizes of the primitives run by the groth16 prover and verifier for the constraint system
+func Get_micro_sizes(stats Constraint_Stats) Micro_Sizes {
+	return Micro_Sizes{
+		Nb_constraints: stats.Nb_constraints,
+		Msm_size:       stats.Nb_internal_variables + stats.Nb_secret_variables + stats.Nb_public_variables,
+		Fft_size:       1 << bits.Len(uint(stats.Nb_constraints-1)),
+		Nb_pairs:       GROTH16_PAIRS,
+	}
+}
+
+// Run_micro compiles the circuit to get the sizes of the primitives of its groth16 proofs and benchmarks the
+// primitives of gnark-crypto at these sizes: the MSMs on G1 and G2, the FFT and inverse FFT, a pairing and the
+// multi-pairing of a verification. With GPU acceleration, the MSMs and the NTT of icicle are also benchmarked on the
+// curves where the prover of gnark uses icicle. Each primitive is run num_reps times.
+func Run_micro(cfg Config, circuit_name string, circuit frontend.Circuit, num_reps int) error {
+	if num_reps <= 0 {
+		return fmt.Errorf("the number of repetitions must be positive")
+	}
+	ccs, err := frontend.Compile(cfg.Curve_id.ScalarField(), r1cs.NewBuilder, circuit)
+	if err != nil {
+		return err
+	}
+	stats := Get_constraint_stats(ccs)
+	sizes := Get_micro_sizes(stats)
+	fmt.Printf("Sizes for %s on %s: MSM %d, FFT %d, multi-pairing %d pairs\n", circuit_name, cfg.Curve_id.String(),
+		sizes.Msm_size, sizes.Fft_size, sizes.Nb_pairs)
+
+	primitives, err := curve_primitives(cfg.Curve_id, sizes)
+	if err != nil {
+		return err
+	}
+	var GPU_name string
+	if cfg.GPU_Acc {
+		gpu_primitives, err := icicle_primitives(cfg.Curve_id, sizes)
+		if err != nil {
+			return err
+		}
+		if len(gpu_primitives) == 0 {
+			fmt.Println("icicle is not used by gnark on", cfg.Curve_id.String(), "the primitives only run on the CPU")
+		} else {
+			gpu.Init_NVML()
+			_, GPU_name = gpu.Get_device(0)
+		}
+		primitives = append(primitives, gpu_primitives...)
+	}
+
+	results := make([]Micro_Result, len(primitives))
+	for p, prim := range primitives {
+		acc := map[bool]string{true: "GPU", false: "CPU"}[prim.gpu]
+		fmt.Printf("Generating the inputs of %s (%s, size %d)...\n", prim.name, acc, prim.size)
+		run, release, err := prim.prepare()
+		if err != nil {
+			return err
+		}
+		results[p] = Micro_Result{Name: prim.name, Size: prim.size, GPU_Acc: prim.gpu}
+		for i := 0; i < num_reps; i++ {
+			start := time.Now()
+			err = run()
+			duration := time.Since(start)
+			if err != nil {
+				return fmt.Errorf("%s failed: %v", prim.name, err)
+			}
+			results[p].Durations = append(results[p].Durations, duration)
+		}
+		if release != nil {
+			release()
+		}
+		fmt.Printf("%s (%s) took %s on average\n", prim.name, acc, average_duration(results[p].Durations).Round(time.Microsecond))
+	}
+
+	params := micro_params{
+		Circuit:  circuit_name,
+		Curve:    cfg.Curve_id.String(),
+		Acc:      map[bool]string{true: "GPU", false: "CPU"}[cfg.GPU_Acc],
+		GPU_name: GPU_name,
+		Num_reps: num_reps,
+		Sizes:    sizes,
+		Stats:    stats,
+	}
+	return write_micro_results(params, results)
+}
+
+func average_duration(durations []time.Duration) time.Duration {
+	var sum time.Duration
+	for _, d := range durations {
+		sum += d
+	}
+	return sum / time.Duration(len(durations))
+}
+
+// write_micro_results writes the parameters, the duration of each repetition and the summary in a new output folder
+func write_micro_results(params micro_params, results []Micro_Result) error {
+	outp_folderpath, err := create_output_folder()
+	if err != nil {
+		return err
+	}
+	data_json, err := json.MarshalIndent(params, "", "    ")
+	if err != nil {
+		return err
+	}
+	write_JSON_file(fmt.Sprintf("%s/micro_parameters.json", outp_folderpath), data_json)
+
+	name := func(r Micro_Result) string {
+		if r.GPU_Acc {
+			return r.Name + " (GPU)"
+		}
+		return r.Name
+	}
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d.Microseconds())/1000.0, 'f', 3, 64)
+	}
+
+	// Write the duration of each repetition, one column per primitive
+	var data_csv [][]string
+	header := []string{"Repetition"}
+	for _, r := range results {
+		header = append(header, name(r))
+	}
+	data_csv = append(data_csv, header)
+	for i := 0; i < params.Num_reps; i++ {
+		row := []string{strconv.Itoa(i)}
+		for _, r := range results {
+			row = append(row, ms(r.Durations[i]))
+		}
+		data_csv = append(data_csv, row)
+	}
+	write_CSV_file(fmt.Sprintf("%s/micro_results.csv", outp_folderpath), data_csv)
+
+	// Write the summary
+	data_csv = data_csv[:0]
+	data_csv = append(data_csv, []string{"Primitive", "Accelerator", "Size", "Avg (ms)", "Min (ms)", "Max (ms)"})
+	for _, r := range results {
+		min, max := r.Durations[0], r.Durations[0]
+		for _, d := range r.Durations {
+			if d < min {
+				min = d
+			}
+			if d > max {
+				max = d
+			}
+		}
+		data_csv = append(data_csv, []string{r.Name, map[bool]string{true: "GPU", false: "CPU"}[r.GPU_Acc], strconv.Itoa(r.Size),
+			ms(average_duration(r.Durations)), ms(min), ms(max)})
+	}
+	write_CSV_file(fmt.Sprintf("%s/micro_summary.csv", outp_folderpath), data_csv)
+	fmt.Println("Micro-benchmark results written in", outp_folderpath)
+	return nil
+}
@@ -0,0 +1,497 @@
+package benchmark
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377"
+	fr_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	fft_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/fft"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	fft_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	fft_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761"
+	fr_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr"
+	fft_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/fft"
+)
+
+// gnark-crypto has no interface common to the curves, the primitives are written once per curve
+// The points are random multiples of the generators, like the points of the proving key.
+
+// curve_primitives returns the primitives of gnark-crypto run on the CPU for the curve
+func curve_primitives(curve_id ecc.ID, sizes Micro_Sizes) ([]primitive, error) {
+	switch curve_id {
+	case ecc.BN254:
+		return bn254_primitives(sizes), nil
+	case ecc.BLS12_377:
+		return bls12377_primitives(sizes), nil
+	case ecc.BLS12_381:
+		return bls12381_primitives(sizes), nil
+	case ecc.BW6_761:
+		return bw6761_primitives(sizes), nil
+	}
+	return nil, fmt.Errorf("the micro-benchmarks are not supported on %s", curve_id.String())
+}
+
+// --- bn254 ---
+
+func bn254_random_scalars(n int) ([]fr_bn254.Element, error) {
+	scalars := make([]fr_bn254.Element, n)
+	for i := range scalars {
+		if _, err := scalars[i].SetRandom(); err != nil {
+			return nil, err
+		}
+	}
+	return scalars, nil
+}
+
+func bn254_random_g1(n int) ([]bn254.G1Affine, error) {
+	scalars, err := bn254_random_scalars(n)
+	if err != nil {
+		return nil, err
+	}
+	_, _, g1, _ := bn254.Generators()
+	return bn254.BatchScalarMultiplicationG1(&g1, scalars), nil
+}
+
+func bn254_random_g2(n int) ([]bn254.G2Affine, error) {
+	scalars, err := bn254_random_scalars(n)
+	if err != nil {
+		return nil, err
+	}
+	_, _, _, g2 := bn254.Generators()
+	return bn254.BatchScalarMultiplicationG2(&g2, scalars), nil
+}
+
+func bn254_primitives(sizes Micro_Sizes) []primitive {
+	return []primitive{
+		{name: "MSM G1", size: sizes.Msm_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bn254_random_scalars(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			points, err := bn254_random_g1(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				var res bn254.G1Jac
+				_, err := res.MultiExp(points, scalars, ecc.MultiExpConfig{})
+				return err
+			}, nil, nil
+		}},
+		{name: "MSM G2", size: sizes.Msm_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bn254_random_scalars(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			points, err := bn254_random_g2(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				var res bn254.G2Jac
+				_, err := res.MultiExp(points, scalars, ecc.MultiExpConfig{})
+				return err
+			}, nil, nil
+		}},
+		{name: "FFT", size: sizes.Fft_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bn254_random_scalars(sizes.Fft_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			domain := fft_bn254.NewDomain(uint64(sizes.Fft_size))
+			return func() error {
+				domain.FFT(scalars, fft_bn254.DIF)
+				return nil
+			}, nil, nil
+		}},
+		{name: "Inverse FFT", size: sizes.Fft_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bn254_random_scalars(sizes.Fft_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			domain := fft_bn254.NewDomain(uint64(sizes.Fft_size))
+			return func() error {
+				domain.FFTInverse(scalars, fft_bn254.DIT)
+				return nil
+			}, nil, nil
+		}},
+		{name: "Pairing", size: 1, prepare: func() (func() error, func(), error) {
+			P, err := bn254_random_g1(1)
+			if err != nil {
+				return nil, nil, err
+			}
+			Q, err := bn254_random_g2(1)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				_, err := bn254.Pair(P, Q)
+				return err
+			}, nil, nil
+		}},
+		{name: "Multi-pairing", size: sizes.Nb_pairs, prepare: func() (func() error, func(), error) {
+			P, err := bn254_random_g1(sizes.Nb_pairs)
+			if err != nil {
+				return nil, nil, err
+			}
+			Q, err := bn254_random_g2(sizes.Nb_pairs)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				_, err := bn254.Pair(P, Q)
+				return err
+			}, nil, nil
+		}},
+	}
+}
+
+// --- bls12-377 ---
+
+func bls12377_random_scalars(n int) ([]fr_bls12377.Element, error) {
+	scalars := make([]fr_bls12377.Element, n)
+	for i := range scalars {
+		if _, err := scalars[i].SetRandom(); err != nil {
+			return nil, err
+		}
+	}
+	return scalars, nil
+}
+
+func bls12377_random_g1(n int) ([]bls12377.G1Affine, error) {
+	scalars, err := bls12377_random_scalars(n)
+	if err != nil {
+		return nil, err
+	}
+	_, _, g1, _ := bls12377.Generators()
+	return bls12377.BatchScalarMultiplicationG1(&g1, scalars), nil
+}
+
+func bls12377_random_g2(n int) ([]bls12377.G2Affine, error) {
+	scalars, err := bls12377_random_scalars(n)
+	if err != nil {
+		return nil, err
+	}
+	_, _, _, g2 := bls12377.Generators()
+	return bls12377.BatchScalarMultiplicationG2(&g2, scalars), nil
+}
+
+func bls12377_primitives(sizes Micro_Sizes) []primitive {
+	return []primitive{
+		{name: "MSM G1", size: sizes.Msm_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bls12377_random_scalars(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			points, err := bls12377_random_g1(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				var res bls12377.G1Jac
+				_, err := res.MultiExp(points, scalars, ecc.MultiExpConfig{})
+				return err
+			}, nil, nil
+		}},
+		{name: "MSM G2", size: sizes.Msm_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bls12377_random_scalars(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			points, err := bls12377_random_g2(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				var res bls12377.G2Jac
+				_, err := res.MultiExp(points, scalars, ecc.MultiExpConfig{})
+				return err
+			}, nil, nil
+		}},
+		{name: "FFT", size: sizes.Fft_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bls12377_random_scalars(sizes.Fft_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			domain := fft_bls12377.NewDomain(uint64(sizes.Fft_size))
+			return func() error {
+				domain.FFT(scalars, fft_bls12377.DIF)
+				return nil
+			}, nil, nil
+		}},
+		{name: "Inverse FFT", size: sizes.Fft_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bls12377_random_scalars(sizes.Fft_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			domain := fft_bls12377.NewDomain(uint64(sizes.Fft_size))
+			return func() error {
+				domain.FFTInverse(scalars, fft_bls12377.DIT)
+				return nil
+			}, nil, nil
+		}},
+		{name: "Pairing", size: 1, prepare: func() (func() error, func(), error) {
+			P, err := bls12377_random_g1(1)
+			if err != nil {
+				return nil, nil, err
+			}
+			Q, err := bls12377_random_g2(1)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				_, err := bls12377.Pair(P, Q)
+				return err
+			}, nil, nil
+		}},
+		{name: "Multi-pairing", size: sizes.Nb_pairs, prepare: func() (func() error, func(), error) {
+			P, err := bls12377_random_g1(sizes.Nb_pairs)
+			if err != nil {
+				return nil, nil, err
+			}
+			Q, err := bls12377_random_g2(sizes.Nb_pairs)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				_, err := bls12377.Pair(P, Q)
+				return err
+			}, nil, nil
+		}},
+	}
+}
+
+// --- bls12-381 ---
+
+func bls12381_random_scalars(n int) ([]fr_bls12381.Element, error) {
+	scalars := make([]fr_bls12381.Element, n)
+	for i := range scalars {
+		if _, err := scalars[i].SetRandom(); err != nil {
+			return nil, err
+		}
+	}
+	return scalars, nil
+}
+
+func bls12381_random_g1(n int) ([]bls12381.G1Affine, error) {
+	scalars, err := bls12381_random_scalars(n)
+	if err != nil {
+		return nil, err
+	}
+	_, _, g1, _ := bls12381.Generators()
+	return bls12381.BatchScalarMultiplicationG1(&g1, scalars), nil
+}
+
+func bls12381_random_g2(n int) ([]bls12381.G2Affine, error) {
+	scalars, err := bls12381_random_scalars(n)
+	if err != nil {
+		return nil, err
+	}
+	_, _, _, g2 := bls12381.Generators()
+	return bls12381.BatchScalarMultiplicationG2(&g2, scalars), nil
+}
+
+func bls12381_primitives(sizes Micro_Sizes) []primitive {
+	return []primitive{
+		{name: "MSM G1", size: sizes.Msm_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bls12381_random_scalars(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			points, err := bls12381_random_g1(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				var res bls12381.G1Jac
+				_, err := res.MultiExp(points, scalars, ecc.MultiExpConfig{})
+				return err
+			}, nil, nil
+		}},
+		{name: "MSM G2", size: sizes.Msm_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bls12381_random_scalars(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			points, err := bls12381_random_g2(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				var res bls12381.G2Jac
+				_, err := res.MultiExp(points, scalars, ecc.MultiExpConfig{})
+				return err
+			}, nil, nil
+		}},
+		{name: "FFT", size: sizes.Fft_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bls12381_random_scalars(sizes.Fft_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			domain := fft_bls12381.NewDomain(uint64(sizes.Fft_size))
+			return func() error {
+				domain.FFT(scalars, fft_bls12381.DIF)
+				return nil
+			}, nil, nil
+		}},
+		{name: "Inverse FFT", size: sizes.Fft_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bls12381_random_scalars(sizes.Fft_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			domain := fft_bls12381.NewDomain(uint64(sizes.Fft_size))
+			return func() error {
+				domain.FFTInverse(scalars, fft_bls12381.DIT)
+				return nil
+			}, nil, nil
+		}},
+		{name: "Pairing", size: 1, prepare: func() (func() error, func(), error) {
+			P, err := bls12381_random_g1(1)
+			if err != nil {
+				return nil, nil, err
+			}
+			Q, err := bls12381_random_g2(1)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				_, err := bls12381.Pair(P, Q)
+				return err
+			}, nil, nil
+		}},
+		{name: "Multi-pairing", size: sizes.Nb_pairs, prepare: func() (func() error, func(), error) {
+			P, err := bls12381_random_g1(sizes.Nb_pairs)
+			if err != nil {
+				return nil, nil, err
+			}
+			Q, err := bls12381_random_g2(sizes.Nb_pairs)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				_, err := bls12381.Pair(P, Q)
+				return err
+			}, nil, nil
+		}},
+	}
+}
+
+// --- bw6-761 ---
+
+func bw6761_random_scalars(n int) ([]fr_bw6761.Element, error) {
+	scalars := make([]fr_bw6761.Element, n)
+	for i := range scalars {
+		if _, err := scalars[i].SetRandom(); err != nil {
+			return nil, err
+		}
+	}
+	return scalars, nil
+}
+
+func bw6761_random_g1(n int) ([]bw6761.G1Affine, error) {
+	scalars, err := bw6761_random_scalars(n)
+	if err != nil {
+		return nil, err
+	}
+	_, _, g1, _ := bw6761.Generators()
+	return bw6761.BatchScalarMultiplicationG1(&g1, scalars), nil
+}
+
+func bw6761_random_g2(n int) ([]bw6761.G2Affine, error) {
+	scalars, err := bw6761_random_scalars(n)
+	if err != nil {
+		return nil, err
+	}
+	_, _, _, g2 := bw6761.Generators()
+	return bw6761.BatchScalarMultiplicationG2(&g2, scalars), nil
+}
+
+func bw6761_primitives(sizes Micro_Sizes) []primitive {
+	return []primitive{
+		{name: "MSM G1", size: sizes.Msm_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bw6761_random_scalars(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			points, err := bw6761_random_g1(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				var res bw6761.G1Jac
+				_, err := res.MultiExp(points, scalars, ecc.MultiExpConfig{})
+				return err
+			}, nil, nil
+		}},
+		{name: "MSM G2", size: sizes.Msm_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bw6761_random_scalars(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			points, err := bw6761_random_g2(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				var res bw6761.G2Jac
+				_, err := res.MultiExp(points, scalars, ecc.MultiExpConfig{})
+				return err
+			}, nil, nil
+		}},
+		{name: "FFT", size: sizes.Fft_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bw6761_random_scalars(sizes.Fft_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			domain := fft_bw6761.NewDomain(uint64(sizes.Fft_size))
+			return func() error {
+				domain.FFT(scalars, fft_bw6761.DIF)
+				return nil
+			}, nil, nil
+		}},
+		{name: "Inverse FFT", size: sizes.Fft_size, prepare: func() (func() error, func(), error) {
+			scalars, err := bw6761_random_scalars(sizes.Fft_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			domain := fft_bw6761.NewDomain(uint64(sizes.Fft_size))
+			return func() error {
+				domain.FFTInverse(scalars, fft_bw6761.DIT)
+				return nil
+			}, nil, nil
+		}},
+		{name: "Pairing", size: 1, prepare: func() (func() error, func(), error) {
+			P, err := bw6761_random_g1(1)
+			if err != nil {
+				return nil, nil, err
+			}
+			Q, err := bw6761_random_g2(1)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				_, err := bw6761.Pair(P, Q)
+				return err
+			}, nil, nil
+		}},
+		{name: "Multi-pairing", size: sizes.Nb_pairs, prepare: func() (func() error, func(), error) {
+			P, err := bw6761_random_g1(sizes.Nb_pairs)
+			if err != nil {
+				return nil, nil, err
+			}
+			Q, err := bw6761_random_g2(sizes.Nb_pairs)
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() error {
+				_, err := bw6761.Pair(P, Q)
+				return err
+			}, nil, nil
+		}},
+	}
+}
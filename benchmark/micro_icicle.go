@@ -0,0 +1,116 @@
+//go:build icicle
+// +build icicle
+
+package benchmark
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	iciclegnark "github.com/ingonyama-zk/iciclegnark/curves/bn254"
+)
+
+// icicle_primitives returns the primitives of icicle used by the groth16 prover of gnark on the curve
+// The prover of gnark only uses icicle on bn254, there are no GPU primitives on the other curves. The inputs are
+// copied to the device before the timing (the prover copies the points of the proving key once, at the setup) and
+// the results are copied back to the host like in the prover.
+func icicle_primitives(curve_id ecc.ID, sizes Micro_Sizes) ([]primitive, error) {
+	if curve_id != ecc.BN254 {
+		return nil, nil
+	}
+	return []primitive{
+		{name: "MSM G1", size: sizes.Msm_size, gpu: true, prepare: func() (func() error, func(), error) {
+			scalars, err := bn254_random_scalars(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			points, err := bn254_random_g1(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			scalars_d := icicle_copy_scalars(scalars)
+			copy_done := make(chan unsafe.Pointer, 1)
+			iciclegnark.CopyPointsToDevice(points, len(points)*fp.Bytes*2, copy_done)
+			points_d := <-copy_done
+			return func() error {
+					_, _, err := iciclegnark.MsmOnDevice(scalars_d, points_d, sizes.Msm_size, true)
+					return err
+				}, func() {
+					iciclegnark.FreeDevicePointer(scalars_d)
+					iciclegnark.FreeDevicePointer(points_d)
+				}, nil
+		}},
+		{name: "MSM G2", size: sizes.Msm_size, gpu: true, prepare: func() (func() error, func(), error) {
+			scalars, err := bn254_random_scalars(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			points, err := bn254_random_g2(sizes.Msm_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			scalars_d := icicle_copy_scalars(scalars)
+			copy_done := make(chan unsafe.Pointer, 1)
+			iciclegnark.CopyG2PointsToDevice(points, len(points)*fp.Bytes*4, copy_done)
+			points_d := <-copy_done
+			return func() error {
+					_, _, err := iciclegnark.MsmG2OnDevice(scalars_d, points_d, sizes.Msm_size, true)
+					return err
+				}, func() {
+					iciclegnark.FreeDevicePointer(scalars_d)
+					iciclegnark.FreeDevicePointer(points_d)
+				}, nil
+		}},
+		{name: "NTT", size: sizes.Fft_size, gpu: true, prepare: func() (func() error, func(), error) {
+			scalars, err := bn254_random_scalars(sizes.Fft_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			twiddles_d, err := iciclegnark.GenerateTwiddleFactors(sizes.Fft_size, false)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not generate the twiddle factors: %v", err)
+			}
+			scalars_d := icicle_copy_scalars(scalars)
+			// The output of the NTT is written in a second buffer of the same size
+			out_d := icicle_copy_scalars(make([]fr_bn254.Element, sizes.Fft_size))
+			return func() error {
+					iciclegnark.NttOnDevice(out_d, scalars_d, twiddles_d, nil, sizes.Fft_size, sizes.Fft_size, sizes.Fft_size*fr_bn254.Bytes, false)
+					return nil
+				}, func() {
+					iciclegnark.FreeDevicePointer(scalars_d)
+					iciclegnark.FreeDevicePointer(out_d)
+					iciclegnark.FreeDevicePointer(twiddles_d)
+				}, nil
+		}},
+		{name: "Inverse NTT", size: sizes.Fft_size, gpu: true, prepare: func() (func() error, func(), error) {
+			scalars, err := bn254_random_scalars(sizes.Fft_size)
+			if err != nil {
+				return nil, nil, err
+			}
+			twiddles_inv_d, err := iciclegnark.GenerateTwiddleFactors(sizes.Fft_size, true)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not generate the twiddle factors: %v", err)
+			}
+			scalars_d := icicle_copy_scalars(scalars)
+			return func() error {
+					// The inverse NTT allocates its output, it is freed like in the prover
+					out_d := iciclegnark.INttOnDevice(scalars_d, twiddles_inv_d, nil, sizes.Fft_size, sizes.Fft_size*fr_bn254.Bytes, false)
+					iciclegnark.FreeDevicePointer(out_d)
+					return nil
+				}, func() {
+					iciclegnark.FreeDevicePointer(scalars_d)
+					iciclegnark.FreeDevicePointer(twiddles_inv_d)
+				}, nil
+		}},
+	}, nil
+}
+
+// icicle_copy_scalars copies the scalars to the device (out of the Montgomery form) and returns the device pointer
+func icicle_copy_scalars(scalars []fr_bn254.Element) unsafe.Pointer {
+	copy_done := make(chan unsafe.Pointer, 1)
+	iciclegnark.CopyToDevice(scalars, len(scalars)*fr_bn254.Bytes, copy_done)
+	return <-copy_done
+}
@@ -0,0 +1,15 @@
+//go:build !icicle
+// +build !icicle
+
+package benchmark
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// icicle_primitives returns an error, icicle is only linked with the icicle build tag
+func icicle_primitives(curve_id ecc.ID, sizes Micro_Sizes) ([]primitive, error) {
+	return nil, fmt.Errorf("icicle acceleration requested but the program was compiled without the 'icicle' build tag")
+}
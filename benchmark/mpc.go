@@ -0,0 +1,164 @@
+package benchmark
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/bits"
+	"strconv"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	"github.com/consensys/gnark/constraint"
+	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
+)
+
+// Number of contributions to phase 1 of the MPC setup
+// Phase 1 (powers of tau) does not depend on the circuit, in practice it is taken from a public ceremony. One
+// contribution is enough so that the powers of tau are not the generators given by the initialization.
+const MPC_PHASE1_CONTRIBUTIONS = 1
+
+// MPC_Step holds the duration of a step of the MPC setup and the size of the file it outputs
+// The steps that don't output a file (the verifications and the key extraction) have a size of -1 and the files
+// that are not output by a step of their own (the evaluations of phase 2) have a duration of -1.
+type MPC_Step struct {
+	Name     string
+	Duration time.Duration
+	Size     int64
+}
+
+// check_mpc returns an error if the MPC setup is not supported for the configuration
+func check_mpc(cfg Config) error {
+	if cfg.MPC_contributions > 0 && cfg.Curve_id != ecc.BN254 {
+		return fmt.Errorf("the MPC setup is only supported on bn254")
+	}
+	return nil
+}
+
+// check_mpc_commitments returns an error if the MPC setup is used for a constraint system with commitments
+// The keys extracted by the MPC setup of gnark have no commitment keys.
+func check_mpc_commitments(cfg Config, ccs constraint.ConstraintSystem) error {
+	if nb := Nb_commitments(ccs); cfg.MPC_contributions > 0 && nb > 0 {
+		return fmt.Errorf("the MPC setup of gnark does not support commitments and the circuit has %d "+
+			"(added by std/rangecheck, logderivlookup and uints, used by sha256, keccak, ...)", nb)
+	}
+	return nil
+}
+
+// transfer serializes the parameters sent to a participant and deserializes them into its copy dst
+func transfer(src io.WriterTo, dst io.ReaderFrom) error {
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf); err != nil {
+		return err
+	}
+	_, err := dst.ReadFrom(&buf)
+	return err
+}
+
+// mpc_setup generates the groth16 keys of the constraint system with the MPC setup of gnark instead of groth16.Setup
+// Phase 1 is initialized for the size of the FFT domain of the circuit and phase 2 is initialized from the R1CS.
+// Each participant receives a copy of the last parameters, adds its contribution and sends its parameters back to
+// the coordinator, who verifies the contribution against the previous parameters. The keys are then extracted from
+// the last parameters of both phases. Each step is timed and the size of the serialized parameters it outputs (the
+// files exchanged with the participants) is measured. The constraint system must have been checked with Check_support.
+func mpc_setup(ccs constraint.ConstraintSystem, nb_contributions int) (groth16.ProvingKey, groth16.VerifyingKey, []MPC_Step, error) {
+	r1cs, ok := ccs.(*cs_bn254.R1CS)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("the MPC setup is only supported for a R1CS on bn254")
+	}
+	var steps []MPC_Step
+	add_step := func(name string, start time.Time, file io.WriterTo) error {
+		step := MPC_Step{Name: name, Duration: time.Since(start), Size: -1}
+		if file != nil {
+			size, err := file.WriteTo(io.Discard)
+			if err != nil {
+				return err
+			}
+			step.Size = size
+		}
+		fmt.Printf("%s took %s\n", name, step.Duration.Round(time.Millisecond))
+		steps = append(steps, step)
+		return nil
+	}
+
+	// Phase 1 holds 2^power powers of tau, as many as the size of the FFT domain of the prover
+	power := bits.Len(uint(ccs.GetNbConstraints() - 1))
+	fmt.Printf("Initializing phase 1 with 2^%d powers of tau...\n", power)
+	start := time.Now()
+	srs1 := mpcsetup.InitPhase1(power)
+	if err := add_step("Phase 1 initialization", start, &srs1); err != nil {
+		return nil, nil, nil, err
+	}
+	for i := 1; i <= MPC_PHASE1_CONTRIBUTIONS; i++ {
+		var next mpcsetup.Phase1
+		if err := transfer(&srs1, &next); err != nil {
+			return nil, nil, nil, err
+		}
+		start = time.Now()
+		next.Contribute()
+		if err := add_step(fmt.Sprintf("Phase 1 contribution %d", i), start, &next); err != nil {
+			return nil, nil, nil, err
+		}
+		start = time.Now()
+		if err := mpcsetup.VerifyPhase1(&srs1, &next); err != nil {
+			return nil, nil, nil, fmt.Errorf("contribution %d of phase 1 is invalid: %v", i, err)
+		}
+		add_step(fmt.Sprintf("Phase 1 verification %d", i), start, nil)
+		srs1 = next
+	}
+
+	// Phase 2 depends on the circuit, the evaluations are kept by the coordinator for the key extraction
+	fmt.Println("Initializing phase 2...")
+	start = time.Now()
+	srs2, evals := mpcsetup.InitPhase2(r1cs, &srs1)
+	if err := add_step("Phase 2 initialization", start, &srs2); err != nil {
+		return nil, nil, nil, err
+	}
+	evals_size, err := evals.WriteTo(io.Discard)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	steps = append(steps, MPC_Step{Name: "Phase 2 evaluations", Duration: -1, Size: evals_size})
+	for i := 1; i <= nb_contributions; i++ {
+		var next mpcsetup.Phase2
+		if err := transfer(&srs2, &next); err != nil {
+			return nil, nil, nil, err
+		}
+		start = time.Now()
+		next.Contribute()
+		if err := add_step(fmt.Sprintf("Phase 2 contribution %d", i), start, &next); err != nil {
+			return nil, nil, nil, err
+		}
+		start = time.Now()
+		if err := mpcsetup.VerifyPhase2(&srs2, &next); err != nil {
+			return nil, nil, nil, fmt.Errorf("contribution %d of phase 2 is invalid: %v", i, err)
+		}
+		add_step(fmt.Sprintf("Phase 2 verification %d", i), start, nil)
+		srs2 = next
+	}
+
+	// Extract the keys from the last parameters
+	start = time.Now()
+	pk, vk := mpcsetup.ExtractKeys(&srs1, &srs2, &evals, ccs.GetNbConstraints())
+	add_step("Key extraction", start, nil)
+	return &pk, &vk, steps, nil
+}
+
+// write_mpc_steps writes the duration (in ms) and the output size (in bytes) of each step of the MPC setup
+func write_mpc_steps(outp_folderpath string, steps []MPC_Step) error {
+	var data_csv [][]string
+	data_csv = append(data_csv, []string{"Step", "Duration", "File size"})
+	for _, s := range steps {
+		duration_str, size_str := "", ""
+		if s.Duration >= 0 {
+			duration_str = strconv.FormatFloat(float64(s.Duration.Microseconds())/1000.0, 'f', 3, 64)
+		}
+		if s.Size >= 0 {
+			size_str = strconv.FormatInt(s.Size, 10)
+		}
+		data_csv = append(data_csv, []string{s.Name, duration_str, size_str})
+	}
+	return write_CSV_file(fmt.Sprintf("%s/mpc_setup.csv", outp_folderpath), data_csv)
+}
@@ -0,0 +1,109 @@
+package benchmark
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-377"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bw6-761"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bls12377 "github.com/consensys/gnark/backend/groth16/bls12-377"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	groth16_bw6761 "github.com/consensys/gnark/backend/groth16/bw6-761"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+// run_negative checks that the unsatisfiable assignments, the tampered public inputs and the tampered proofs
+// are all rejected and keeps track of how long it takes to reject each of them
+func run_negative(outp *Benchmark_Output, ccs constraint.ConstraintSystem, pk groth16.ProvingKey, vk groth16.VerifyingKey, scalarfield *big.Int,
+	GPU_Acc bool, invalid_assignments []frontend.Circuit, proofs []groth16.Proof, public_witnesses []witness.Witness) error {
+
+	for i := 0; i < len(invalid_assignments); i++ {
+		fmt.Printf("Negative run %d/%d\n", i+1, len(invalid_assignments))
+		invalid_witness, err := frontend.NewWitness(invalid_assignments[i], scalarfield)
+		if err != nil {
+			return err
+		}
+		tampered_public_witness, err := invalid_witness.Public()
+		if err != nil {
+			return err
+		}
+		// The solver should fail on the unsatisfiable assignment before any proof is generated
+		outp.Start_invalid_proof_gen = append(outp.Start_invalid_proof_gen, time.Now())
+		_, err = prove(ccs, pk, invalid_witness, GPU_Acc)
+		outp.End_invalid_proof_gen = append(outp.End_invalid_proof_gen, time.Now())
+		outp.Invalid_witness_rejected = append(outp.Invalid_witness_rejected, err != nil)
+
+		// A proof that could not be generated cannot be tampered with, the tampered cases of the run are not run
+		// and are left out of the results
+		outp.Tampered_skipped = append(outp.Tampered_skipped, proofs[i] == nil)
+		if proofs[i] == nil {
+			fmt.Println("The proof of the run could not be generated, the tampered cases are skipped")
+			now := time.Now()
+			outp.Start_tampered_input_ver = append(outp.Start_tampered_input_ver, now)
+			outp.End_tampered_input_ver = append(outp.End_tampered_input_ver, now)
+			outp.Tampered_input_rejected = append(outp.Tampered_input_rejected, false)
+			outp.Start_tampered_proof_ver = append(outp.Start_tampered_proof_ver, now)
+			outp.End_tampered_proof_ver = append(outp.End_tampered_proof_ver, now)
+			outp.Tampered_proof_rejected = append(outp.Tampered_proof_rejected, false)
+			continue
+		}
+		// The valid proof is checked against the public inputs of the unsatisfiable assignment
+		outp.Start_tampered_input_ver = append(outp.Start_tampered_input_ver, time.Now())
+		err = groth16.Verify(proofs[i], vk, tampered_public_witness)
+		outp.End_tampered_input_ver = append(outp.End_tampered_input_ver, time.Now())
+		outp.Tampered_input_rejected = append(outp.Tampered_input_rejected, err != nil)
+
+		// The tampered proof is checked against the genuine public inputs
+		tampered_proof, err := tamper_proof(proofs[i])
+		if err != nil {
+			return err
+		}
+		outp.Start_tampered_proof_ver = append(outp.Start_tampered_proof_ver, time.Now())
+		err = groth16.Verify(tampered_proof, vk, public_witnesses[i])
+		outp.End_tampered_proof_ver = append(outp.End_tampered_proof_ver, time.Now())
+		outp.Tampered_proof_rejected = append(outp.Tampered_proof_rejected, err != nil)
+	}
+	return nil
+}
+
+// tamper_proof returns a copy of the proof where the generator of G1 is added to the Krs point
+// The result is still a valid curve point so the verifier has to run the pairing check to reject it
+func tamper_proof(proof groth16.Proof) (groth16.Proof, error) {
+	// Copy the proof by serializing and deserializing it
+	var buf bytes.Buffer
+	_, err := proof.WriteRawTo(&buf)
+	if err != nil {
+		return nil, err
+	}
+	tampered := groth16.NewProof(proof.CurveID())
+	_, err = tampered.ReadFrom(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := tampered.(type) {
+	case *groth16_bn254.Proof:
+		_, _, g1, _ := bn254.Generators()
+		p.Krs.Add(&p.Krs, &g1)
+	case *groth16_bls12377.Proof:
+		_, _, g1, _ := bls12377.Generators()
+		p.Krs.Add(&p.Krs, &g1)
+	case *groth16_bls12381.Proof:
+		_, _, g1, _ := bls12381.Generators()
+		p.Krs.Add(&p.Krs, &g1)
+	case *groth16_bw6761.Proof:
+		_, _, g1, _ := bw6761.Generators()
+		p.Krs.Add(&p.Krs, &g1)
+	default:
+		return nil, fmt.Errorf("curve %s is not supported", proof.CurveID().String())
+	}
+	return tampered, nil
+}
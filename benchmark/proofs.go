@@ -0,0 +1,110 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend/schema"
+)
+
+// save_proofs writes the verifying key and the proof and public witness of each run in the proofs folder
+// Every artifact is written in the gnark binary format (.bin) and in JSON (.json)
+func save_proofs(outp_folderpath string, vk groth16.VerifyingKey, proofs []groth16.Proof, public_witnesses []witness.Witness, s *schema.Schema) error {
+	proofs_folderpath := fmt.Sprintf("%s/proofs", outp_folderpath)
+	err := os.MkdirAll(proofs_folderpath, 0755)
+	if err != nil {
+		return err
+	}
+
+	// Write the verifying key
+	err = write_binary_file(fmt.Sprintf("%s/verifying_key.bin", proofs_folderpath), vk)
+	if err != nil {
+		return err
+	}
+	data_json, err := json.MarshalIndent(vk, "", "    ")
+	if err != nil {
+		return err
+	}
+	err = write_JSON_file(fmt.Sprintf("%s/verifying_key.json", proofs_folderpath), data_json)
+	if err != nil {
+		return err
+	}
+
+	// The JSON of the public witnesses only has the public fields of the circuit, with the full schema the secret
+	// fields would be written as null values
+	var public_schema *schema.Schema
+	if s != nil {
+		public_schema = &schema.Schema{Fields: public_fields(s.Fields), NbPublic: s.NbPublic}
+	}
+
+	// Write the proof and the public witness of each run
+	for i := 0; i < len(proofs); i++ {
+		// Runs where the proof generation failed don't have a proof to save
+		if proofs[i] == nil {
+			continue
+		}
+		err = write_binary_file(fmt.Sprintf("%s/proof_%d.bin", proofs_folderpath, i), proofs[i])
+		if err != nil {
+			return err
+		}
+		data_json, err = json.MarshalIndent(proofs[i], "", "    ")
+		if err != nil {
+			return err
+		}
+		err = write_JSON_file(fmt.Sprintf("%s/proof_%d.json", proofs_folderpath, i), data_json)
+		if err != nil {
+			return err
+		}
+
+		err = write_binary_file(fmt.Sprintf("%s/public_witness_%d.bin", proofs_folderpath, i), public_witnesses[i])
+		if err != nil {
+			return err
+		}
+		// The JSON of the public witness needs the schema of the circuit, which is not known for a loaded constraint system
+		if s == nil {
+			continue
+		}
+		data_json, err = public_witnesses[i].ToJSON(public_schema)
+		if err != nil {
+			return err
+		}
+		err = write_JSON_file(fmt.Sprintf("%s/public_witness_%d.json", proofs_folderpath, i), data_json)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// public_fields returns the fields of the schema that are public or that contain public fields
+func public_fields(fields []schema.Field) []schema.Field {
+	var res []schema.Field
+	for _, f := range fields {
+		// The structs and the arrays of structs are described by their sub-fields, the leaves and the arrays of
+		// leaves by their visibility
+		if len(f.SubFields) > 0 {
+			f.SubFields = public_fields(f.SubFields)
+			if len(f.SubFields) > 0 {
+				res = append(res, f)
+			}
+		} else if f.Visibility == schema.Public {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+func write_binary_file(filepath string, obj io.WriterTo) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = obj.WriteTo(file)
+	return err
+}
@@ -0,0 +1,309 @@
+package benchmark
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/logger"
+	"github.com/consensys/gnark/profile"
+	"github.com/rs/zerolog"
+
+	"gnark_on_icicle/gpu"
+)
+
+// Config holds the benchmark parameters that are common to all the circuits
+type Config struct {
+	Curve_id ecc.ID
+	GPU_Acc  bool
+	// Benchmark unsatisfiable assignments and tampered proofs/public inputs after the valid runs
+	Negative bool
+	// Save the verifying key and the proof and public witness of each run in the output folder
+	Save_proofs bool
+	// Export the Solidity verifier and measure the gas used to verify each proof in an EVM (bn254 only)
+	Solidity bool
+	// Profile the constraints of the circuit and write the profile in the output folder
+	Profile bool
+	// Number of contributions to phase 2 of the MPC setup that replaces groth16.Setup (bn254 only), 0 for groth16.Setup
+	MPC_contributions int
+	// Verify the proofs of the runs in a batch with one randomized multi-pairing check after the individual verifications
+	Batch_verify bool
+	// Runtime parameters of the circuit, set by the circuit packages and saved in benchmark_parameters.json
+	Circuit_params map[string]int
+}
+
+// Result holds the artifacts of a benchmark that can be reused by another benchmark (e.g. to verify the proofs recursively)
+type Result struct {
+	Ccs              constraint.ConstraintSystem
+	Vk               groth16.VerifyingKey
+	Proofs           []groth16.Proof
+	Public_witnesses []witness.Witness
+	// Duration of the setup and of the proof generation and verification of each run
+	Setup_time   time.Duration
+	Prove_times  []time.Duration
+	Verify_times []time.Duration
+	// Output folder where the results of the benchmark were written
+	Outp_folderpath string
+}
+
+// Run compiles the circuit, runs the groth16 setup and then generates and verifies a proof for each assignment.
+// In the negative mode, invalid_assignments[i] must be an unsatisfiable version of assignments[i] that differs
+// from it in at least one public input.
+func Run(cfg Config, circuit_name string, circuit frontend.Circuit, assignments []frontend.Circuit, invalid_assignments []frontend.Circuit) error {
+	_, err := Run_with_result(cfg, circuit_name, circuit, assignments, invalid_assignments)
+	return err
+}
+
+// Run_with_result is the same as Run but also returns the constraint system, the verifying key, the proofs and
+// the public witnesses of the benchmark
+func Run_with_result(cfg Config, circuit_name string, circuit frontend.Circuit, assignments []frontend.Circuit, invalid_assignments []frontend.Circuit) (*Result, error) {
+	if cfg.Negative && len(invalid_assignments) != len(assignments) {
+		return nil, fmt.Errorf("expected %d invalid assignments for the negative mode, got %d", len(assignments), len(invalid_assignments))
+	}
+	if err := check_mpc(cfg); err != nil {
+		return nil, err
+	}
+	var err error
+
+	buf := capture_logs()
+	// Create a variable to save all the values from the benchmark
+	outp := new_output(cfg, circuit_name, "groth16", len(assignments))
+
+	// Initialize the GPU logging if GPU acceleration is used
+	// The sampling is also stopped when the benchmark returns early with an error
+	stop_sampling := start_GPU_sampling(cfg, &outp)
+	defer stop_sampling()
+
+	scalarfield := cfg.Curve_id.ScalarField()
+	// Keep track of the beginning and end time of each step
+	outp.Start_arith = time.Now()
+	// Profile the compilation to attribute the constraints to the lines of the circuit
+	var p *profile.Profile
+	if cfg.Profile {
+		p, outp.Profile_path, err = start_profile()
+		if err != nil {
+			return nil, err
+		}
+		// The temporary profile is copied in the output folder by Compile and removed on every return
+		defer os.Remove(outp.Profile_path)
+	}
+	// compiles our circuit into a R1CS
+	ccs, err := frontend.Compile(scalarfield, r1cs.NewBuilder, circuit)
+	if p != nil {
+		p.Stop()
+		outp.Profile_top = p.Top()
+	}
+	if err != nil {
+		return nil, err
+	}
+	outp.Nb_constraints = ccs.GetNbConstraints()
+	outp.End_arith = time.Now()
+	outp.Constraint_stats = Get_constraint_stats(ccs)
+	if err := Check_support(cfg, ccs); err != nil {
+		return nil, err
+	}
+
+	// groth16 zkSNARK: Setup
+	// The MPC setup replaces the setup with toxic randomness, the whole ceremony is measured as the setup
+	fmt.Println("Running setup...")
+	var pk groth16.ProvingKey
+	var vk groth16.VerifyingKey
+	outp.Start_setup = time.Now()
+	if cfg.MPC_contributions > 0 {
+		pk, vk, outp.MPC_steps, err = mpc_setup(ccs, cfg.MPC_contributions)
+	} else {
+		pk, vk, err = groth16.Setup(ccs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	outp.End_setup = time.Now()
+
+	// Keep the proofs and public witnesses of the valid runs for the negative tests
+	proofs := make([]groth16.Proof, len(assignments))
+	public_witnesses := make([]witness.Witness, len(assignments))
+	// Keep the full witness of the first run to measure its size
+	var first_witness witness.Witness
+	for i := 0; i < len(assignments); i++ {
+		fmt.Printf("Benchmark run %d/%d\n", i+1, len(assignments))
+		// Witness genration
+		outp.Start_witness_gen = append(outp.Start_witness_gen, time.Now())
+		witness, _ := frontend.NewWitness(assignments[i], scalarfield)
+		publicWitness, _ := witness.Public()
+		outp.End_witness_gen = append(outp.End_witness_gen, time.Now())
+		// groth16: Prove & Verify
+		outp.Start_proof_gen_func = append(outp.Start_proof_gen_func, time.Now())
+		proof, err := prove(ccs, pk, witness, cfg.GPU_Acc)
+		outp.End_proof_gen_func = append(outp.End_proof_gen_func, time.Now())
+		outp.Proof_generated = append(outp.Proof_generated, err == nil)
+		public_witnesses[i] = publicWitness
+		if i == 0 {
+			first_witness = witness
+		}
+		if err != nil {
+			// The proof returned with the error is a nil pointer of the curve, it is not verified and proofs[i] is
+			// left nil so that the later steps skip the run
+			fmt.Println(err)
+			fmt.Println("Proof generation failed, the proof is not verified")
+			now := time.Now()
+			outp.Start_proof_ver = append(outp.Start_proof_ver, now)
+			outp.End_proof_ver = append(outp.End_proof_ver, now)
+			outp.Proof_valid = append(outp.Proof_valid, false)
+			continue
+		}
+		outp.Start_proof_ver = append(outp.Start_proof_ver, time.Now())
+		err = groth16.Verify(proof, vk, publicWitness)
+		outp.End_proof_ver = append(outp.End_proof_ver, time.Now())
+		if err == nil {
+			fmt.Println("Proof is valid!")
+		} else {
+			fmt.Println("Proof is invalid: ", err)
+		}
+		outp.Proof_valid = append(outp.Proof_valid, err == nil)
+		proofs[i] = proof
+	}
+
+	if cfg.Negative {
+		err = run_negative(&outp, ccs, pk, vk, scalarfield, cfg.GPU_Acc, invalid_assignments, proofs, public_witnesses)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	outp.GPU_samples = stop_sampling()
+	// Verify the proofs of all the runs at once
+	if cfg.Batch_verify {
+		err = run_batch(&outp, vk, proofs, public_witnesses)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Measure the size of the artifacts of the first run
+	if len(proofs) > 0 && proofs[0] != nil {
+		fmt.Println("Measuring artifact sizes...")
+		outp.Artifacts, err = measure_artifacts(cfg.Curve_id, ccs, pk, vk, proofs[0], first_witness, public_witnesses[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Verify the proofs with the Solidity verifier
+	if cfg.Solidity {
+		fmt.Println("Verifying proofs in the EVM...")
+		outp.Solidity_output, err = run_solidity(vk, proofs, public_witnesses)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Save_proofs {
+		outp.Schema, err = frontend.NewSchema(circuit)
+		if err != nil {
+			return nil, err
+		}
+		outp.Vk = vk
+		outp.Proofs = proofs
+		outp.Public_witnesses = public_witnesses
+	}
+	outp.Dbg_log = buf.String()
+	fmt.Println("Compiling benchmark results...")
+	outp_folderpath, err := Compile(outp)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Ccs: ccs, Vk: vk, Proofs: proofs, Public_witnesses: public_witnesses, Setup_time: outp.End_setup.Sub(outp.Start_setup),
+		Outp_folderpath: outp_folderpath}
+	for i := range proofs {
+		res.Prove_times = append(res.Prove_times, outp.End_proof_gen_func[i].Sub(outp.Start_proof_gen_func[i]))
+		res.Verify_times = append(res.Verify_times, outp.End_proof_ver[i].Sub(outp.Start_proof_ver[i]))
+	}
+	return res, nil
+}
+
+// Check_support returns an error if an option of the configuration does not support the constraint system
+// It is called right after the compilation so that an unsupported benchmark fails before the setup.
+func Check_support(cfg Config, ccs constraint.ConstraintSystem) error {
+	if err := check_mpc_commitments(cfg, ccs); err != nil {
+		return err
+	}
+	if cfg.Solidity {
+		if err := check_solidity(cfg.Curve_id, ccs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// capture_logs overtakes the gnark logger with another one that outputs to a buffer and the console
+// The buffer is parsed by Compile to extract the duration of the solver and of the prover
+func capture_logs() *bytes.Buffer {
+	var buf bytes.Buffer
+	multi := zerolog.MultiLevelWriter(zerolog.ConsoleWriter{Out: os.Stdout}, &buf)
+	logger.Set(zerolog.New(multi).With().Timestamp().Logger())
+	return &buf
+}
+
+// new_output creates the output of a benchmark with the parameters of the configuration
+func new_output(cfg Config, circuit_name string, backend_name string, num_runs int) Benchmark_Output {
+	return Benchmark_Output{
+		Circuit:        circuit_name,
+		Backend:        backend_name,
+		Num_runs:       num_runs,
+		GPU_Acc:        cfg.GPU_Acc,
+		Curve:          cfg.Curve_id.String(),
+		Negative:       cfg.Negative,
+		Save_proofs:    cfg.Save_proofs,
+		Solidity:       cfg.Solidity,
+		Profile:        cfg.Profile,
+		Circuit_params: cfg.Circuit_params,
+
+		MPC_contributions: cfg.MPC_contributions,
+		Batch_verify:      cfg.Batch_verify,
+	}
+}
+
+// start_GPU_sampling starts sampling the GPU in a goroutine if GPU acceleration is used
+// The returned function stops the sampling and returns the samples, it can be called several times (e.g. deferred
+// for the early returns) and only stops the sampling the first time
+func start_GPU_sampling(cfg Config, outp *Benchmark_Output) func() []gpu.GPU_Sample {
+	if !cfg.GPU_Acc {
+		return func() []gpu.GPU_Sample { return nil }
+	}
+	// Create a channel to signal the GPU sampling function to stop
+	stop := make(chan struct{})
+	// Create a channel to receive the GPU samples
+	GPU_samples := make(chan []gpu.GPU_Sample)
+	// Initilaize NVML
+	gpu.Init_NVML()
+	// Get the GPU device and its name
+	device, name := gpu.Get_device(0)
+	outp.GPU_Name = name
+	// Start the GPU sampling funciton as a goroutine
+	go gpu.GPU_Periodic_Samples(gpu.SAMPLIMG_PERIOD, device, stop, GPU_samples)
+	var samples []gpu.GPU_Sample
+	stopped := false
+	return func() []gpu.GPU_Sample {
+		if !stopped {
+			stopped = true
+			// Signal the GPU sampling function to stop
+			close(stop)
+			// Wait for the periodic function to return the result
+			samples = <-GPU_samples
+		}
+		return samples
+	}
+}
+
+// prove runs groth16.Prove with the icicle acceleration if GPU_Acc is set
+func prove(ccs constraint.ConstraintSystem, pk groth16.ProvingKey, witness witness.Witness, GPU_Acc bool) (groth16.Proof, error) {
+	if GPU_Acc {
+		return groth16.Prove(ccs, pk, witness, backend.WithIcicleAcceleration())
+	}
+	return groth16.Prove(ccs, pk, witness)
+}
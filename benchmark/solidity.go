@@ -0,0 +1,168 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Solidity_Output holds the exported Solidity verifier and the results of the on-chain verification of each proof
+type Solidity_Output struct {
+	Verifier        string
+	Bytecode_size   int
+	Deployment_gas  uint64
+	Start_evm_ver   []time.Time
+	End_evm_ver     []time.Time
+	Execution_gas   []uint64
+	Transaction_gas []uint64
+	Evm_proof_valid []bool
+	// Runs where the proof generation failed are not sent to the verifier
+	Evm_called []bool
+}
+
+// check_solidity makes sure the Solidity verifier can be exported and compiled before the setup of the benchmark
+// The verifier exported by gnark does not verify the commitments, so the constraint systems with commitments are
+// rejected as soon as they are compiled.
+func check_solidity(curve_id ecc.ID, ccs constraint.ConstraintSystem) error {
+	if curve_id != ecc.BN254 {
+		return fmt.Errorf("the Solidity verifier is only available for bn254")
+	}
+	if nb := Nb_commitments(ccs); nb > 0 {
+		return fmt.Errorf("the Solidity verifier does not support commitments and the circuit has %d", nb)
+	}
+	_, err := exec.LookPath("solc")
+	if err != nil {
+		return fmt.Errorf("solc is required to compile the Solidity verifier: %v", err)
+	}
+	return nil
+}
+
+// run_solidity exports the Solidity verifier from the verifying key, compiles it with solc and verifies
+// each proof inside an in-process EVM to measure the gas used. Only bn254 is supported since the EVM
+// only has precompiles for this curve, the constraint system must have been checked with check_solidity.
+func run_solidity(vk groth16.VerifyingKey, proofs []groth16.Proof, public_witnesses []witness.Witness) (Solidity_Output, error) {
+	var outp Solidity_Output
+
+	// Export the verifier
+	var buf bytes.Buffer
+	err := vk.ExportSolidity(&buf)
+	if err != nil {
+		return outp, err
+	}
+	outp.Verifier = buf.String()
+
+	// Compile the verifier
+	bytecode, err := compile_verifier(outp.Verifier)
+	if err != nil {
+		return outp, err
+	}
+	outp.Bytecode_size = len(bytecode)
+
+	// Deploy the verifier in the EVM
+	evm_cfg := &runtime.Config{ChainConfig: params.MergedTestChainConfig, Random: &common.Hash{}, GasLimit: math.MaxUint64}
+	_, address, left_over_gas, err := runtime.Create(bytecode, evm_cfg)
+	if err != nil {
+		return outp, fmt.Errorf("error deploying the verifier: %v", err)
+	}
+	outp.Deployment_gas = evm_cfg.GasLimit - left_over_gas
+
+	// Verify each proof by calling verifyProof(uint256[8] proof, uint256[N] input)
+	nb_public := vk.NbPublicWitness()
+	selector := crypto.Keccak256([]byte(fmt.Sprintf("verifyProof(uint256[8],uint256[%d])", nb_public)))[:4]
+	for i := 0; i < len(proofs); i++ {
+		// Runs where the proof generation failed are counted as invalid without calling the verifier
+		if proofs[i] == nil {
+			now := time.Now()
+			outp.Start_evm_ver = append(outp.Start_evm_ver, now)
+			outp.End_evm_ver = append(outp.End_evm_ver, now)
+			outp.Evm_proof_valid = append(outp.Evm_proof_valid, false)
+			outp.Evm_called = append(outp.Evm_called, false)
+			outp.Execution_gas = append(outp.Execution_gas, 0)
+			outp.Transaction_gas = append(outp.Transaction_gas, 0)
+			continue
+		}
+		input, err := verifier_calldata(selector, proofs[i], public_witnesses[i])
+		if err != nil {
+			return outp, err
+		}
+		outp.Start_evm_ver = append(outp.Start_evm_ver, time.Now())
+		_, left_over_gas, err = runtime.Call(address, input, evm_cfg)
+		outp.End_evm_ver = append(outp.End_evm_ver, time.Now())
+		// The verifier reverts if the proof is invalid
+		outp.Evm_proof_valid = append(outp.Evm_proof_valid, err == nil)
+		outp.Evm_called = append(outp.Evm_called, true)
+		execution_gas := evm_cfg.GasLimit - left_over_gas
+		intrinsic_gas, err := core.IntrinsicGas(input, nil, false, true, true, true)
+		if err != nil {
+			return outp, err
+		}
+		outp.Execution_gas = append(outp.Execution_gas, execution_gas)
+		outp.Transaction_gas = append(outp.Transaction_gas, execution_gas+intrinsic_gas)
+	}
+	return outp, nil
+}
+
+// compile_verifier compiles the verifier with the solc binary found in the PATH and returns its creation bytecode
+func compile_verifier(verifier string) ([]byte, error) {
+	solc, err := exec.LookPath("solc")
+	if err != nil {
+		return nil, err
+	}
+	tmp_dir, err := os.MkdirTemp("", "verifier")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp_dir)
+	err = os.WriteFile(filepath.Join(tmp_dir, "Verifier.sol"), []byte(verifier), 0644)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(solc, "--optimize", "--bin", "-o", tmp_dir, filepath.Join(tmp_dir, "Verifier.sol"))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("error compiling the Solidity verifier: %v\n%s", err, out)
+	}
+	bin, err := os.ReadFile(filepath.Join(tmp_dir, "Verifier.bin"))
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimSpace(string(bin)))
+}
+
+// verifier_calldata encodes the call to verifyProof
+// The first 256 bytes of the raw proof are the points (A, B, C) in the EIP-197 format expected by the verifier
+func verifier_calldata(selector []byte, proof groth16.Proof, public_witness witness.Witness) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := proof.WriteRawTo(&buf)
+	if err != nil {
+		return nil, err
+	}
+	input := append([]byte{}, selector...)
+	input = append(input, buf.Bytes()[:8*fr_bn254.Bytes]...)
+	public_inputs, ok := public_witness.Vector().(fr_bn254.Vector)
+	if !ok {
+		return nil, witness.ErrInvalidWitness
+	}
+	for i := range public_inputs {
+		b := public_inputs[i].Bytes()
+		input = append(input, b[:]...)
+	}
+	return input, nil
+}
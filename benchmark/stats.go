@@ -0,0 +1,143 @@
+package benchmark
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/profile"
+	pprof "github.com/google/pprof/profile"
+)
+
+// Constraint_Stats holds the statistics of a constraint system
+// The public variables of an R1CS include the constant wire.
+type Constraint_Stats struct {
+	Nb_constraints        int `json:"Constraints"`
+	Nb_internal_variables int `json:"Internal variables"`
+	Nb_secret_variables   int `json:"Secret variables"`
+	Nb_public_variables   int `json:"Public variables"`
+	Nb_coefficients       int `json:"Coefficients"`
+	Nb_commitments        int `json:"Commitments"`
+}
+
+// Get_constraint_stats returns the statistics of the constraint system
+func Get_constraint_stats(ccs constraint.ConstraintSystem) Constraint_Stats {
+	internal, secret, public := ccs.GetNbVariables()
+	return Constraint_Stats{
+		Nb_constraints:        ccs.GetNbConstraints(),
+		Nb_internal_variables: internal,
+		Nb_secret_variables:   secret,
+		Nb_public_variables:   public,
+		Nb_coefficients:       ccs.GetNbCoefficients(),
+		Nb_commitments:        Nb_commitments(ccs),
+	}
+}
+
+// Nb_commitments returns the number of commitments of the constraint system (e.g. used by the log-derivative
+// arguments of the range checks and lookups)
+func Nb_commitments(ccs constraint.ConstraintSystem) int {
+	switch commitments := ccs.GetCommitments().(type) {
+	case constraint.Groth16Commitments:
+		return len(commitments)
+	case constraint.PlonkCommitments:
+		return len(commitments)
+	}
+	return 0
+}
+
+// start_profile starts a gnark profile that attributes each constraint to the line of the circuit which created it
+// The profile is written to a temporary file when it is stopped, Compile then copies it to the output folder and the
+// caller removes it.
+func start_profile() (*profile.Profile, string, error) {
+	file, err := os.CreateTemp("", "gnark-*.pprof")
+	if err != nil {
+		return nil, "", err
+	}
+	file.Close()
+	return profile.Start(profile.WithPath(file.Name())), file.Name(), nil
+}
+
+// write_profile copies the pprof file of the profile to the output folder and writes its text summary
+// The pprof file can be opened with `go tool pprof -top constraint_profile.pprof` (or -web, -list Define...)
+func write_profile(outp_folderpath string, outp Benchmark_Output) error {
+	data, err := os.ReadFile(outp.Profile_path)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(fmt.Sprintf("%s/constraint_profile.pprof", outp_folderpath), data, 0644)
+	if err != nil {
+		return err
+	}
+
+	lines, err := constraints_per_line(data)
+	if err != nil {
+		return err
+	}
+
+	var summary strings.Builder
+	s := outp.Constraint_stats
+	fmt.Fprintf(&summary, "Circuit: %s (%s)\n", outp.Circuit, outp.Curve)
+	fmt.Fprintf(&summary, "Constraints: %d\n", s.Nb_constraints)
+	fmt.Fprintf(&summary, "Internal variables: %d\n", s.Nb_internal_variables)
+	fmt.Fprintf(&summary, "Secret variables: %d\n", s.Nb_secret_variables)
+	fmt.Fprintf(&summary, "Public variables: %d\n", s.Nb_public_variables)
+	fmt.Fprintf(&summary, "Coefficients: %d\n", s.Nb_coefficients)
+	fmt.Fprintf(&summary, "Commitments: %d\n\n", s.Nb_commitments)
+	fmt.Fprintf(&summary, "Constraints per line of the circuit:\n")
+	fmt.Fprintf(&summary, "%12s %8s  %s\n", "Constraints", "Share", "Line")
+	for _, l := range lines {
+		fmt.Fprintf(&summary, "%12d %7.2f%%  %s\n", l.count, 100*float64(l.count)/float64(s.Nb_constraints), l.line)
+	}
+	fmt.Fprintf(&summary, "\nConstraints per function of gnark:\n")
+	summary.WriteString(outp.Profile_top)
+	return os.WriteFile(fmt.Sprintf("%s/constraint_profile.txt", outp_folderpath), []byte(summary.String()), 0644)
+}
+
+// Prefix of the functions of this project in the profile
+const MODULE_PREFIX = "gnark_on_icicle/"
+
+type line_count struct {
+	line  string
+	count int64
+}
+
+// constraints_per_line attributes each constraint of the profile to the innermost line of this project in its call
+// stack (e.g. a line of Define or of a helper function of the circuit package), sorted by number of constraints.
+// The constraints created outside of the code of this project (e.g. by the commitments) are counted as "other".
+// gnark only records the 20 innermost calls of each constraint, so the constraints created deep inside the
+// standard library (e.g. by the hash functions) can also end up in "other".
+func constraints_per_line(data []byte) ([]line_count, error) {
+	p, err := pprof.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64)
+	for _, sample := range p.Sample {
+		line := "other"
+		// The locations go from the leaf to the root of the call stack
+	search:
+		for _, location := range sample.Location {
+			for _, l := range location.Line {
+				if l.Function != nil && strings.HasPrefix(l.Function.SystemName, MODULE_PREFIX) {
+					line = fmt.Sprintf("%s %s:%d", l.Function.Name, l.Function.Filename, l.Line)
+					break search
+				}
+			}
+		}
+		counts[line] += sample.Value[0]
+	}
+	lines := make([]line_count, 0, len(counts))
+	for line, count := range counts {
+		lines = append(lines, line_count{line, count})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].count != lines[j].count {
+			return lines[i].count > lines[j].count
+		}
+		return lines[i].line < lines[j].line
+	})
+	return lines, nil
+}
@@ -0,0 +1,160 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+type verification_params struct {
+	Curve               string `json:"Curve"`
+	Backend             string `json:"Backend"`
+	Verifying_key       string `json:"Verifying key"`
+	Proof               string `json:"Proof"`
+	Public_witness      string `json:"Public witness"`
+	Nb_public_inputs    int    `json:"Number of public inputs"`
+	Num_reps            int    `json:"Number of repetitions"`
+	Proof_valid         bool   `json:"Valid proof"`
+	Verification_result string `json:"Verification result"`
+}
+
+// Verify_from_files loads a verifying key, a proof and a public witness saved in the gnark binary format
+// (as written with -save_proofs) and verifies the proof num_reps times to time the verification
+// backend_name is groth16 or plonk, the format of the keys and of the proofs of gnark depends on the backend.
+func Verify_from_files(curve_id ecc.ID, backend_name string, vk_path string, proof_path string, public_witness_path string, num_reps int) error {
+	if num_reps <= 0 {
+		return fmt.Errorf("the number of repetitions must be positive")
+	}
+
+	// Create the verifying key and the proof of the backend, verify is called on the loaded artifacts
+	var vk interface {
+		io.ReaderFrom
+		NbPublicWitness() int
+	}
+	var proof io.ReaderFrom
+	var verify func(public_witness witness.Witness) error
+	switch backend_name {
+	case "groth16":
+		groth16_vk, groth16_proof := groth16.NewVerifyingKey(curve_id), groth16.NewProof(curve_id)
+		vk, proof = groth16_vk, groth16_proof
+		verify = func(public_witness witness.Witness) error {
+			return groth16.Verify(groth16_proof, groth16_vk, public_witness)
+		}
+	case "plonk":
+		plonk_vk, plonk_proof := plonk.NewVerifyingKey(curve_id), plonk.NewProof(curve_id)
+		vk, proof = plonk_vk, plonk_proof
+		verify = func(public_witness witness.Witness) error {
+			return plonk.Verify(plonk_proof, plonk_vk, public_witness)
+		}
+	default:
+		return fmt.Errorf("backend %s unknown, the backend must be groth16 or plonk", backend_name)
+	}
+
+	// Load the artifacts and keep track of how long it takes
+	start_load := time.Now()
+	err := read_binary_file(vk_path, vk)
+	if err != nil {
+		return fmt.Errorf("error reading verifying key: %v", err)
+	}
+	err = read_binary_file(proof_path, proof)
+	if err != nil {
+		return fmt.Errorf("error reading proof: %v", err)
+	}
+	public_witness, err := witness.New(curve_id.ScalarField())
+	if err != nil {
+		return err
+	}
+	err = read_binary_file(public_witness_path, public_witness)
+	if err != nil {
+		return fmt.Errorf("error reading public witness: %v", err)
+	}
+	end_load := time.Now()
+
+	// Verify the proof several times
+	start_ver := make([]time.Time, num_reps)
+	end_ver := make([]time.Time, num_reps)
+	var ver_err error
+	for i := 0; i < num_reps; i++ {
+		fmt.Printf("Verification %d/%d\n", i+1, num_reps)
+		start_ver[i] = time.Now()
+		ver_err = verify(public_witness)
+		end_ver[i] = time.Now()
+	}
+	if ver_err == nil {
+		fmt.Println("Proof is valid!")
+	} else {
+		fmt.Println("Proof is invalid: ", ver_err)
+	}
+
+	outp_folderpath, err := create_output_folder()
+	if err != nil {
+		return err
+	}
+	// Create a JSON file to save the verification parameters
+	ver_params := verification_params{
+		Curve:            curve_id.String(),
+		Backend:          backend_name,
+		Verifying_key:    vk_path,
+		Proof:            proof_path,
+		Public_witness:   public_witness_path,
+		Nb_public_inputs: vk.NbPublicWitness(),
+		Num_reps:         num_reps,
+		Proof_valid:      ver_err == nil,
+	}
+	if ver_err != nil {
+		ver_params.Verification_result = ver_err.Error()
+	}
+	data_json, err := json.MarshalIndent(ver_params, "", "    ")
+	if err != nil {
+		return err
+	}
+	write_JSON_file(fmt.Sprintf("%s/verification_parameters.json", outp_folderpath), data_json)
+
+	// Write the duration of each verification
+	var data_csv [][]string
+	data_csv = append(data_csv, []string{"Repetition", "Proof verification"})
+	var ver_dur_cumul, ver_dur_min, ver_dur_max time.Duration
+	for i := 0; i < num_reps; i++ {
+		ver_dur := end_ver[i].Sub(start_ver[i])
+		ver_dur_cumul += ver_dur
+		if i == 0 || ver_dur < ver_dur_min {
+			ver_dur_min = ver_dur
+		}
+		if ver_dur > ver_dur_max {
+			ver_dur_max = ver_dur
+		}
+		data_csv = append(data_csv, []string{strconv.FormatInt(int64(i), 10), strconv.FormatFloat(float64(ver_dur.Microseconds())/1000.0, 'f', 3, 64)})
+	}
+	write_CSV_file(fmt.Sprintf("%s/verification_results.csv", outp_folderpath), data_csv)
+
+	// Write the summary
+	data_csv = data_csv[:0]
+	data_csv = append(data_csv, []string{"Loading", "Avg proof verification", "Min proof verification", "Max proof verification"})
+	data_csv = append(data_csv, []string{strconv.FormatFloat(float64(end_load.Sub(start_load).Microseconds())/1000.0, 'f', 3, 64),
+		strconv.FormatFloat(float64(ver_dur_cumul.Microseconds())/1000.0/float64(num_reps), 'f', 3, 64),
+		strconv.FormatFloat(float64(ver_dur_min.Microseconds())/1000.0, 'f', 3, 64),
+		strconv.FormatFloat(float64(ver_dur_max.Microseconds())/1000.0, 'f', 3, 64)})
+	write_CSV_file(fmt.Sprintf("%s/verification_summary.csv", outp_folderpath), data_csv)
+	fmt.Println("Verification results written in", outp_folderpath)
+
+	return nil
+}
+
+func read_binary_file(filepath string, obj io.ReaderFrom) error {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = obj.ReadFrom(file)
+	return err
+}
@@ -0,0 +1,241 @@
+package bls
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+
+	"gnark_on_icicle/benchmark"
+)
+
+// Domain separation tag of the BLS signature scheme with the public keys in G1 and the signatures in G2
+// (proof of possession ciphersuite of the IETF draft, used by Ethereum)
+const DST = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+
+// Size in bytes of the random messages
+const MSG_SIZE = 32
+
+// Signature_input holds a message signed by k signers, their public keys and the aggregated signature
+// All the signers sign the same message, with k = 1 it is a single BLS signature.
+type Signature_input struct {
+	Msg       []byte
+	Pub_keys  []bls12381.G1Affine
+	Signature bls12381.G2Affine
+}
+
+/* Helper functions */
+
+// Hash_to_G2 hashes the message to G2 like the signers, the hash is computed outside of the circuit
+func Hash_to_G2(msg []byte) (bls12381.G2Affine, error) {
+	return bls12381.HashToG2(msg, []byte(DST))
+}
+
+func Gen_rand_inputs(n int, signers int) ([]Signature_input, error) {
+	if signers < 1 {
+		return nil, fmt.Errorf("the number of signers must be at least 1")
+	}
+	_, _, g1, _ := bls12381.Generators()
+	inputs := make([]Signature_input, n)
+
+	for i := 0; i < n; i++ {
+		// Generate a random message
+		msg := make([]byte, MSG_SIZE)
+		_, err := rand.Read(msg)
+		if err != nil {
+			return nil, err
+		}
+		h, err := Hash_to_G2(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		// Each signer has a new key pair, the signature is the sum of the signatures of the signers
+		// sk_j random, pk_j = [sk_j]g1, sig = sum_j [sk_j]H(msg)
+		inputs[i] = Signature_input{Msg: msg, Pub_keys: make([]bls12381.G1Affine, signers)}
+		var agg_sig bls12381.G2Jac
+		for j := 0; j < signers; j++ {
+			var sk fr_bls12381.Element
+			if _, err := sk.SetRandom(); err != nil {
+				return nil, err
+			}
+			sk_int := sk.BigInt(new(big.Int))
+			inputs[i].Pub_keys[j].ScalarMultiplication(&g1, sk_int)
+			var sig bls12381.G2Affine
+			sig.ScalarMultiplication(&h, sk_int)
+			agg_sig.AddMixed(&sig)
+		}
+		inputs[i].Signature.FromJacobian(&agg_sig)
+	}
+
+	return inputs, nil
+}
+
+// Function to read file and extract the signatures
+// Each line contains the message, the aggregated signature and the public keys of the signers, all in hexadecimal.
+// The points are in the compressed format of gnark-crypto (48 bytes for G1, 96 bytes for G2).
+// All the lines must have the same number of signers.
+func Parse_file(file_path string) ([]Signature_input, error) {
+	// Open the file
+	file, err := os.Open(file_path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var inputs []Signature_input
+
+	scanner := bufio.NewScanner(file)
+	// The lines get long with many signers
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line_num := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		line_num++
+		parts := strings.Split(line, " ")
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid line format: %s", line)
+		}
+		// All the signatures need to have the same number of signers to use the same circuit
+		if len(inputs) > 0 && len(parts)-2 != len(inputs[0].Pub_keys) {
+			return nil, fmt.Errorf("invalid signature not %d signers at line %d", len(inputs[0].Pub_keys), line_num)
+		}
+		var input Signature_input
+		// Read the message
+		input.Msg, err = hex.DecodeString(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("error decoding message at line %d: %v", line_num, err)
+		}
+		// Read the signature, SetBytes checks that the point is in G2
+		sig_bytes, err := hex.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("error decoding signature at line %d: %v", line_num, err)
+		}
+		if _, err := input.Signature.SetBytes(sig_bytes); err != nil {
+			return nil, fmt.Errorf("error decoding signature at line %d: %v", line_num, err)
+		}
+		// Read the public keys, SetBytes checks that the points are in G1
+		input.Pub_keys = make([]bls12381.G1Affine, len(parts)-2)
+		for j := range input.Pub_keys {
+			key_bytes, err := hex.DecodeString(parts[j+2])
+			if err != nil {
+				return nil, fmt.Errorf("error decoding public key %d at line %d: %v", j, line_num, err)
+			}
+			if _, err := input.Pub_keys[j].SetBytes(key_bytes); err != nil {
+				return nil, fmt.Errorf("error decoding public key %d at line %d: %v", j, line_num, err)
+			}
+		}
+		inputs = append(inputs, input)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return inputs, nil
+}
+
+// BLSCircuit defines the verification of a BLS12-381 signature aggregated over k signers of the same message
+// The fields of BLS12-381 are emulated in the scalar field of the proving curve.
+// The public keys and the hash of the message to G2 are public, the signature is secret.
+// Hashing to G2 is done outside of the circuit, and the public keys are assumed to be checked (e.g. with a proof
+// of possession) when they are registered, like in light clients. Only the signature is checked to be in G2.
+type BLSCircuit struct {
+	PubKeys   []sw_bls12381.G1Affine `gnark:",public"`
+	MsgHash   sw_bls12381.G2Affine   `gnark:",public"`
+	Signature sw_bls12381.G2Affine
+}
+
+// Define declares the circuit's constraints
+// e(-g1, Signature) * e(sum_j PubKeys[j], MsgHash) == 1
+func (circuit *BLSCircuit) Define(api frontend.API) error {
+	if len(circuit.PubKeys) == 0 {
+		return fmt.Errorf("at least one public key is needed")
+	}
+	curve, err := sw_emulated.New[sw_bls12381.BaseField, sw_bls12381.ScalarField](api, sw_emulated.GetBLS12381Params())
+	if err != nil {
+		return err
+	}
+	pairing, err := sw_bls12381.NewPairing(api)
+	if err != nil {
+		return err
+	}
+
+	// Aggregate the public keys
+	// AddUnified also handles a signer listed twice
+	agg_key := &circuit.PubKeys[0]
+	for j := 1; j < len(circuit.PubKeys); j++ {
+		agg_key = curve.AddUnified(agg_key, &circuit.PubKeys[j])
+	}
+
+	// The pairing check does not check the subgroups
+	pairing.AssertIsOnG2(&circuit.Signature)
+
+	_, _, g1, _ := bls12381.Generators()
+	var neg_g1 bls12381.G1Affine
+	neg_g1.Neg(&g1)
+	neg_g1_circuit := sw_bls12381.NewG1Affine(neg_g1)
+	return pairing.PairingCheck([]*sw_bls12381.G1Affine{&neg_g1_circuit, agg_key}, []*sw_bls12381.G2Affine{&circuit.Signature, &circuit.MsgHash})
+}
+
+func assignment(msg []byte, input Signature_input) (*BLSCircuit, error) {
+	h, err := Hash_to_G2(msg)
+	if err != nil {
+		return nil, err
+	}
+	a := &BLSCircuit{
+		PubKeys:   make([]sw_bls12381.G1Affine, len(input.Pub_keys)),
+		MsgHash:   sw_bls12381.NewG2Affine(h),
+		Signature: sw_bls12381.NewG2Affine(input.Signature),
+	}
+	for j := range input.Pub_keys {
+		a.PubKeys[j] = sw_bls12381.NewG1Affine(input.Pub_keys[j])
+	}
+	return a, nil
+}
+
+// New_circuit returns the circuit verifying a signature aggregated from the given number of signers
+func New_circuit(signers int) *BLSCircuit {
+	return &BLSCircuit{PubKeys: make([]sw_bls12381.G1Affine, signers)}
+}
+
+func Benchmark(cfg benchmark.Config, inputs []Signature_input) error {
+	if len(inputs) == 0 {
+		fmt.Println("No signatures were given. Please check your input!")
+		return nil
+	}
+	signers := len(inputs[0].Pub_keys)
+
+	// Create the circuit assignments
+	// The invalid assignments use the hash of a wrong message so that the signature does not verify
+	assignments := make([]frontend.Circuit, len(inputs))
+	invalid_assignments := make([]frontend.Circuit, len(inputs))
+	var err error
+	for i, input := range inputs {
+		if len(input.Pub_keys) != signers {
+			return fmt.Errorf("all the signatures must have %d signers", signers)
+		}
+		assignments[i], err = assignment(input.Msg, input)
+		if err != nil {
+			return err
+		}
+		wrong_msg := append([]byte{}, input.Msg...)
+		wrong_msg = append(wrong_msg, 1)
+		invalid_assignments[i], err = assignment(wrong_msg, input)
+		if err != nil {
+			return err
+		}
+	}
+
+	cfg.Circuit_params = map[string]int{"BLS signers": signers}
+	return benchmark.Run(cfg, "bls", New_circuit(signers), assignments, invalid_assignments)
+}
@@ -0,0 +1,124 @@
+package circom
+
+import (
+	"fmt"
+	"math/big"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	cs_bn254 "github.com/consensys/gnark/constraint/bn254"
+
+	"gnark_on_icicle/benchmark"
+)
+
+// Circuits of circom are only supported on bn254, the default curve of circom and snarkjs
+const CURVE = ecc.BN254
+
+// To_gnark converts a circom R1CS into a gnark R1CS
+// Each circom constraint gives exactly one gnark constraint and the wire i of circom is the variable i of gnark:
+// the constant 1 and the public wires are public variables, all the other wires are secret variables. The
+// values of the internal wires are computed by the witness calculator of circom, so they are given in the
+// witness and the gnark solver only checks the constraints.
+func To_gnark(r *R1CS) (constraint.ConstraintSystem, error) {
+	if r.Prime.Cmp(CURVE.ScalarField()) != 0 {
+		return nil, fmt.Errorf("the circuit was not compiled for bn254 (prime %s)", r.Prime.String())
+	}
+	ccs := cs_bn254.NewR1CS(len(r.Constraints))
+	blueprint := ccs.AddBlueprint(&constraint.BlueprintGenericR1C{})
+
+	// The name of the constant wire tells gnark that it is not part of the witness
+	ccs.AddPublicVariable("1")
+	for i := 1; i <= r.Nb_public(); i++ {
+		ccs.AddPublicVariable(fmt.Sprintf("w%d", i))
+	}
+	for i := r.Nb_public() + 1; i < r.Nb_wires; i++ {
+		ccs.AddSecretVariable(fmt.Sprintf("w%d", i))
+	}
+
+	to_lc := func(factors []Factor) (constraint.LinearExpression, error) {
+		lc := make(constraint.LinearExpression, len(factors))
+		for k, f := range factors {
+			if int(f.Wire) >= r.Nb_wires {
+				return nil, fmt.Errorf("wire %d out of the %d wires", f.Wire, r.Nb_wires)
+			}
+			lc[k] = ccs.MakeTerm(ccs.FromInterface(f.Coeff), int(f.Wire))
+		}
+		return lc, nil
+	}
+	for j, c := range r.Constraints {
+		var r1c constraint.R1C
+		var err error
+		if r1c.L, err = to_lc(c.A); err != nil {
+			return nil, fmt.Errorf("constraint %d: %v", j, err)
+		}
+		if r1c.R, err = to_lc(c.B); err != nil {
+			return nil, fmt.Errorf("constraint %d: %v", j, err)
+		}
+		if r1c.O, err = to_lc(c.C); err != nil {
+			return nil, fmt.Errorf("constraint %d: %v", j, err)
+		}
+		ccs.AddR1C(r1c, blueprint)
+	}
+	return ccs, nil
+}
+
+// To_gnark_witness converts the values of the wires read from a .wtns file into a full gnark witness
+// The constant 1 is not part of the gnark witness, the public wires come first like in the gnark witness.
+func To_gnark_witness(r *R1CS, prime *big.Int, values []*big.Int) (witness.Witness, error) {
+	if prime.Cmp(r.Prime) != 0 {
+		return nil, fmt.Errorf("the witness and the circuit use different fields")
+	}
+	if len(values) != r.Nb_wires {
+		return nil, fmt.Errorf("the witness has %d wires, the circuit has %d wires", len(values), r.Nb_wires)
+	}
+	if values[0].Cmp(big.NewInt(1)) != 0 {
+		return nil, fmt.Errorf("the first wire of the witness is not the constant 1")
+	}
+	w, err := witness.New(CURVE.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	values_chan := make(chan any)
+	go func() {
+		defer close(values_chan)
+		for _, v := range values[1:] {
+			values_chan <- v
+		}
+	}()
+	err = w.Fill(r.Nb_public(), r.Nb_wires-1-r.Nb_public(), values_chan)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Benchmark converts a circom circuit and its witnesses and benchmarks them with groth16 on bn254
+// The reading and the conversion of the .r1cs file is measured as the arithmetization and the reading and the
+// conversion of each .wtns file as the witness generation.
+func Benchmark(cfg benchmark.Config, r1cs_path string, wtns_paths []string) error {
+	if cfg.Curve_id != CURVE {
+		return fmt.Errorf("circom circuits are only supported on bn254")
+	}
+
+	var r *R1CS
+	load_ccs := func() (constraint.ConstraintSystem, error) {
+		var err error
+		r, err = Read_r1cs(r1cs_path)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Converting the circom circuit: %d constraints, %d wires, %d public outputs, %d public inputs, %d private inputs\n",
+			len(r.Constraints), r.Nb_wires, r.Nb_pub_out, r.Nb_pub_in, r.Nb_prv_in)
+		return To_gnark(r)
+	}
+	load_witness := func(i int) (witness.Witness, error) {
+		prime, values, err := Read_wtns(wtns_paths[i])
+		if err != nil {
+			return nil, err
+		}
+		return To_gnark_witness(r, prime, values)
+	}
+	return benchmark.Run_external(cfg, filepath.Base(r1cs_path), load_ccs, len(wtns_paths), load_witness)
+}
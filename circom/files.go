@@ -0,0 +1,276 @@
+package circom
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+)
+
+// Sections of the .r1cs files
+const (
+	R1CS_HEADER      = 1
+	R1CS_CONSTRAINTS = 2
+	R1CS_WIRE2LABEL  = 3
+)
+
+// Sections of the .wtns files
+const (
+	WTNS_HEADER = 1
+	WTNS_VALUES = 2
+)
+
+// Factor is a term of a linear combination of a circom constraint: Coeff * wire
+type Factor struct {
+	Wire  uint32
+	Coeff *big.Int
+}
+
+// Constraint is a circom constraint A * B - C = 0 where A, B and C are linear combinations of the wires
+type Constraint struct {
+	A, B, C []Factor
+}
+
+// R1CS holds the content of a .r1cs file
+// The wires are ordered as: the constant 1, the public outputs, the public inputs, the private inputs and the
+// internal wires.
+type R1CS struct {
+	Prime       *big.Int
+	Nb_wires    int
+	Nb_pub_out  int
+	Nb_pub_in   int
+	Nb_prv_in   int
+	Constraints []Constraint
+}
+
+// Nb_public returns the number of public wires without the constant 1
+func (r *R1CS) Nb_public() int {
+	return r.Nb_pub_out + r.Nb_pub_in
+}
+
+/* Helper functions */
+
+// file_reader reads the little endian values of the binary formats of circom
+// remaining is the number of bytes left in the current section, a read past the end of the section is an error so
+// that the counts read from the file cannot make the parser allocate more than the size of the file.
+type file_reader struct {
+	r         *bufio.Reader
+	err       error
+	remaining uint64
+}
+
+func (fr *file_reader) read(data any) {
+	if fr.err != nil {
+		return
+	}
+	size := uint64(binary.Size(data))
+	if size > fr.remaining {
+		fr.err = fmt.Errorf("a section is shorter than its content")
+		return
+	}
+	fr.remaining -= size
+	fr.err = binary.Read(fr.r, binary.LittleEndian, data)
+}
+
+func (fr *file_reader) uint32() uint32 {
+	var v uint32
+	fr.read(&v)
+	return v
+}
+
+func (fr *file_reader) uint64() uint64 {
+	var v uint64
+	fr.read(&v)
+	return v
+}
+
+// count returns n if n elements of elem_size bytes fit in the rest of the section, it is checked before the
+// elements are allocated
+func (fr *file_reader) count(n uint32, elem_size uint64) int {
+	if fr.err == nil && uint64(n)*elem_size > fr.remaining {
+		fr.err = fmt.Errorf("%d elements of %d bytes do not fit in the %d bytes left in the section", n, elem_size, fr.remaining)
+	}
+	if fr.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// field_element reads a field element of n8 bytes in little endian (not in the Montgomery form)
+func (fr *file_reader) field_element(n8 int) *big.Int {
+	buf := make([]byte, fr.count(uint32(n8), 1))
+	fr.read(buf)
+	// big.Int uses big endian
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+	return new(big.Int).SetBytes(buf)
+}
+
+// skip skips the rest of the section
+func (fr *file_reader) skip() {
+	if fr.err == nil {
+		_, fr.err = io.CopyN(io.Discard, fr.r, int64(fr.remaining))
+		fr.remaining = 0
+	}
+}
+
+// read_sections reads the header of a binary file of circom and calls read_section for each section
+// The files start with a magic string of 4 bytes, the version and the number of sections. Each section starts
+// with its type and its size in bytes. read_section can only read the bytes of its section, the bytes that it
+// does not read (e.g. of an unknown section) are skipped.
+func read_sections(file_path string, magic string, version uint32, read_section func(fr *file_reader, section uint32)) error {
+	file, err := os.Open(file_path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Magic string, version and number of sections
+	fr := &file_reader{r: bufio.NewReaderSize(file, 1<<20), remaining: 12}
+	file_magic := make([]byte, 4)
+	fr.read(file_magic)
+	if fr.err != nil {
+		return fr.err
+	}
+	if string(file_magic) != magic {
+		return fmt.Errorf("%s is not a .%s file", file_path, magic)
+	}
+	if v := fr.uint32(); fr.err == nil && v != version {
+		return fmt.Errorf("unsupported version %d of the .%s format, only version %d is supported", v, magic, version)
+	}
+	nb_sections := fr.uint32()
+	for i := uint32(0); i < nb_sections && fr.err == nil; i++ {
+		// Type and size of the section
+		fr.remaining = 12
+		section := fr.uint32()
+		fr.remaining = fr.uint64()
+		if fr.err == nil {
+			read_section(fr, section)
+		}
+		fr.skip()
+	}
+	if fr.err != nil {
+		return fmt.Errorf("error reading %s: %v", file_path, fr.err)
+	}
+	return nil
+}
+
+// Is_r1cs_file returns true if the file starts with the magic string of the .r1cs format of circom
+func Is_r1cs_file(file_path string) bool {
+	file, err := os.Open(file_path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	magic := make([]byte, 4)
+	_, err = io.ReadFull(file, magic)
+	return err == nil && string(magic) == "r1cs"
+}
+
+// Read_r1cs reads a .r1cs file (version 1) written by circom
+// The header section has to come before the constraints and the wire labels sections, which is the case for the
+// files of circom.
+// The custom gates of the PLONK extension are not supported.
+func Read_r1cs(file_path string) (*R1CS, error) {
+	var r *R1CS
+	var n8 int
+	var nb_constraints uint32
+	has_labels := false
+	err := read_sections(file_path, "r1cs", 1, func(fr *file_reader, section uint32) {
+		switch section {
+		case R1CS_HEADER:
+			// Size of the field elements in bytes and modulus of the field
+			n8 = int(fr.uint32())
+			r = &R1CS{Prime: fr.field_element(n8)}
+			r.Nb_wires = int(fr.uint32())
+			r.Nb_pub_out = int(fr.uint32())
+			r.Nb_pub_in = int(fr.uint32())
+			r.Nb_prv_in = int(fr.uint32())
+			// Number of labels, they are only used for debugging
+			fr.uint64()
+			nb_constraints = fr.uint32()
+		case R1CS_CONSTRAINTS:
+			if r == nil {
+				fr.err = fmt.Errorf("the constraints come before the header")
+				return
+			}
+			// A constraint has at least the numbers of factors of its 3 linear combinations and a factor has a wire
+			// and a coefficient
+			r.Constraints = make([]Constraint, fr.count(nb_constraints, 12))
+			read_lc := func() []Factor {
+				lc := make([]Factor, fr.count(fr.uint32(), 4+uint64(n8)))
+				for k := range lc {
+					lc[k].Wire = fr.uint32()
+					lc[k].Coeff = fr.field_element(n8)
+					if fr.err != nil {
+						return nil
+					}
+				}
+				return lc
+			}
+			for j := range r.Constraints {
+				r.Constraints[j] = Constraint{A: read_lc(), B: read_lc(), C: read_lc()}
+				if fr.err != nil {
+					break
+				}
+			}
+		case R1CS_WIRE2LABEL:
+			// The labels are only used for debugging, the section has a label of 8 bytes per wire so it bounds the
+			// number of wires of the header
+			if r == nil {
+				fr.err = fmt.Errorf("the wire labels come before the header")
+				return
+			}
+			fr.count(uint32(r.Nb_wires), 8)
+			has_labels = true
+		default:
+			fr.err = fmt.Errorf("unsupported section %d (custom gates are not supported)", section)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if r == nil || r.Constraints == nil || !has_labels {
+		return nil, fmt.Errorf("%s has no header, no constraints or no wire labels", file_path)
+	}
+	if 1+r.Nb_public()+r.Nb_prv_in > r.Nb_wires {
+		return nil, fmt.Errorf("%s has %d wires, less than the constant 1 and the inputs and outputs", file_path, r.Nb_wires)
+	}
+	return r, nil
+}
+
+// Read_wtns reads a .wtns file (version 2) written by the witness calculator of circom
+// It returns the modulus of the field and the values of all the wires.
+func Read_wtns(file_path string) (*big.Int, []*big.Int, error) {
+	var prime *big.Int
+	var values []*big.Int
+	var n8 int
+	var nb_values uint32
+	err := read_sections(file_path, "wtns", 2, func(fr *file_reader, section uint32) {
+		switch section {
+		case WTNS_HEADER:
+			n8 = int(fr.uint32())
+			prime = fr.field_element(n8)
+			nb_values = fr.uint32()
+		case WTNS_VALUES:
+			if prime == nil {
+				fr.err = fmt.Errorf("the values come before the header")
+				return
+			}
+			values = make([]*big.Int, fr.count(nb_values, uint64(n8)))
+			for i := range values {
+				values[i] = fr.field_element(n8)
+			}
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if prime == nil {
+		return nil, nil, fmt.Errorf("%s has no header", file_path)
+	}
+	return prime, values, nil
+}
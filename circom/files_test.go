@@ -0,0 +1,125 @@
+package circom
+
+import (
+	"encoding/binary"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark/backend/groth16"
+)
+
+// testdata/multiply.r1cs is a hand-built circuit with one constraint a * b = out (wires 1, out, a, b) and
+// testdata/multiply.wtns its witness for a = 3 and b = 11
+const (
+	R1CS_FIXTURE = "testdata/multiply.r1cs"
+	WTNS_FIXTURE = "testdata/multiply.wtns"
+)
+
+// The fixture must be converted into a gnark R1CS with its public output and proven and verified with groth16
+func TestConvert_and_prove(t *testing.T) {
+	r, err := Read_r1cs(R1CS_FIXTURE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Constraints) != 1 || r.Nb_wires != 4 || r.Nb_public() != 1 || r.Nb_prv_in != 2 {
+		t.Fatalf("got %d constraints, %d wires, %d public and %d private inputs, expected 1, 4, 1 and 2", len(r.Constraints),
+			r.Nb_wires, r.Nb_public(), r.Nb_prv_in)
+	}
+	prime, values, err := Read_wtns(WTNS_FIXTURE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ccs, err := To_gnark(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full_witness, err := To_gnark_witness(r, prime, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public_witness, err := full_witness.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := groth16.Prove(ccs, pk, full_witness)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16.Verify(proof, vk, public_witness); err != nil {
+		t.Fatal(err)
+	}
+
+	// A witness whose output is not the product of the inputs must not be proven
+	values[1] = big.NewInt(34)
+	wrong_witness, err := To_gnark_witness(r, prime, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := groth16.Prove(ccs, pk, wrong_witness); err == nil {
+		t.Error("a proof was generated for a witness that does not satisfy the constraint")
+	}
+}
+
+// A count of the file that does not fit in its section must be an error instead of an allocation of its size
+func TestCorrupted_counts(t *testing.T) {
+	r1cs, err := os.ReadFile(R1CS_FIXTURE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wtns, err := os.ReadFile(WTNS_FIXTURE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Offsets in the fixtures: the files start with 12 bytes and each section with 12 bytes, the r1cs header has
+	// 64 bytes and the wtns header 40 bytes
+	tests := []struct {
+		name   string
+		data   []byte
+		offset int
+		read   func(path string) error
+	}{
+		{"field size", r1cs, 24, read_r1cs_error},
+		{"number of wires", r1cs, 60, read_r1cs_error},
+		{"number of constraints", r1cs, 84, read_r1cs_error},
+		{"number of factors", r1cs, 100, read_r1cs_error},
+		{"size of a section", r1cs, 16, read_r1cs_error},
+		{"number of values", wtns, 60, read_wtns_error},
+	}
+	for _, test := range tests {
+		data := append([]byte{}, test.data...)
+		binary.LittleEndian.PutUint32(data[test.offset:], 0xffffffff)
+		path := filepath.Join(t.TempDir(), "corrupted")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := test.read(path); err == nil {
+			t.Errorf("%s: the corrupted file was read without an error", test.name)
+		}
+	}
+
+	// A truncated file must also be an error
+	path := filepath.Join(t.TempDir(), "truncated")
+	if err := os.WriteFile(path, r1cs[:len(r1cs)-8], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := read_r1cs_error(path); err == nil || !strings.Contains(err.Error(), "EOF") {
+		t.Errorf("truncated file: expected an EOF error, got %v", err)
+	}
+}
+
+func read_r1cs_error(path string) error {
+	_, err := Read_r1cs(path)
+	return err
+}
+
+func read_wtns_error(path string) error {
+	_, _, err := Read_wtns(path)
+	return err
+}
@@ -0,0 +1,166 @@
+package ecdsa
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	secp256k1_ecdsa "github.com/consensys/gnark-crypto/ecc/secp256k1/ecdsa"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/emulated"
+	std_ecdsa "github.com/consensys/gnark/std/signature/ecdsa"
+
+	"gnark_on_icicle/benchmark"
+)
+
+// Size in bytes of the random messages
+const MSG_SIZE = 32
+
+// Signature_input holds a signed message and the public key of the signer
+// The message is given by its SHA-256 hash reduced to the scalar field of secp256k1
+type Signature_input struct {
+	Msg_hash *big.Int
+	R        *big.Int
+	S        *big.Int
+	Pub_x    *big.Int
+	Pub_y    *big.Int
+}
+
+func Gen_rand_inputs(n int) ([]Signature_input, error) {
+	inputs := make([]Signature_input, n)
+
+	for i := 0; i < n; i++ {
+		// Generate a new key pair and a random message
+		priv_key, err := secp256k1_ecdsa.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		msg := make([]byte, MSG_SIZE)
+		_, err = rand.Read(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		// Sign the SHA-256 hash of the message
+		sig_bin, err := priv_key.Sign(msg, sha256.New())
+		if err != nil {
+			return nil, err
+		}
+		var sig secp256k1_ecdsa.Signature
+		_, err = sig.SetBytes(sig_bin)
+		if err != nil {
+			return nil, err
+		}
+		msg_hash := sha256.Sum256(msg)
+
+		inputs[i] = Signature_input{
+			Msg_hash: secp256k1_ecdsa.HashToInt(msg_hash[:]),
+			R:        new(big.Int).SetBytes(sig.R[:]),
+			S:        new(big.Int).SetBytes(sig.S[:]),
+			Pub_x:    priv_key.PublicKey.A.X.BigInt(new(big.Int)),
+			Pub_y:    priv_key.PublicKey.A.Y.BigInt(new(big.Int)),
+		}
+	}
+
+	return inputs, nil
+}
+
+// Function to read file and extract the signatures
+// Each line contains the message hash, r, s and the coordinates of the public key, all in hexadecimal
+func Parse_file(file_path string) ([]Signature_input, error) {
+	// Open the file
+	file, err := os.Open(file_path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var inputs []Signature_input
+
+	scanner := bufio.NewScanner(file)
+	line_num := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		line_num++
+		parts := strings.Split(line, " ")
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("invalid line format: %s", line)
+		}
+		// Decode the values from hexadecimal strings
+		names := []string{"message hash", "r", "s", "public key x", "public key y"}
+		values := make([]*big.Int, len(parts))
+		for j := range parts {
+			var succ bool
+			values[j], succ = new(big.Int).SetString(parts[j], 16)
+			if !succ {
+				return nil, fmt.Errorf("error decoding %s at line %d: Failed to convert string to big.Int", names[j], line_num)
+			}
+		}
+		inputs = append(inputs, Signature_input{Msg_hash: values[0], R: values[1], S: values[2], Pub_x: values[3], Pub_y: values[4]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return inputs, nil
+}
+
+// ECDSACircuit defines the verification of an ECDSA signature over secp256k1
+// The base and scalar fields of secp256k1 are emulated in the scalar field of the proving curve.
+// The message hash and the public key are public, the signature is secret.
+type ECDSACircuit struct {
+	Sig     std_ecdsa.Signature[emulated.Secp256k1Fr]
+	MsgHash emulated.Element[emulated.Secp256k1Fr]                          `gnark:",public"`
+	Pub     std_ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr] `gnark:",public"`
+}
+
+// Define declares the circuit's constraints
+// Verify(Pub, MsgHash, Sig) == true
+func (circuit *ECDSACircuit) Define(api frontend.API) error {
+	circuit.Pub.Verify(api, sw_emulated.GetSecp256k1Params(), &circuit.MsgHash, &circuit.Sig)
+	return nil
+}
+
+func assignment(input Signature_input) *ECDSACircuit {
+	return &ECDSACircuit{
+		Sig: std_ecdsa.Signature[emulated.Secp256k1Fr]{
+			R: emulated.ValueOf[emulated.Secp256k1Fr](input.R),
+			S: emulated.ValueOf[emulated.Secp256k1Fr](input.S),
+		},
+		MsgHash: emulated.ValueOf[emulated.Secp256k1Fr](input.Msg_hash),
+		Pub: std_ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+			X: emulated.ValueOf[emulated.Secp256k1Fp](input.Pub_x),
+			Y: emulated.ValueOf[emulated.Secp256k1Fp](input.Pub_y),
+		},
+	}
+}
+
+// New_circuit returns the circuit verifying one signature
+func New_circuit() *ECDSACircuit {
+	return &ECDSACircuit{}
+}
+
+func Benchmark(cfg benchmark.Config, inputs []Signature_input) error {
+	if len(inputs) == 0 {
+		fmt.Println("No inputs were given. Please check your input!")
+		return nil
+	}
+	// Create the circuit assignments
+	// The invalid assignments use a wrong message hash so that the signature does not verify
+	assignments := make([]frontend.Circuit, len(inputs))
+	invalid_assignments := make([]frontend.Circuit, len(inputs))
+	for i, input := range inputs {
+		assignments[i] = assignment(input)
+		wrong_input := input
+		wrong_input.Msg_hash = new(big.Int).Add(input.Msg_hash, big.NewInt(1))
+		invalid_assignments[i] = assignment(wrong_input)
+	}
+
+	return benchmark.Run(cfg, "ecdsa", New_circuit(), assignments, invalid_assignments)
+}
@@ -0,0 +1,204 @@
+package eddsa
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	crypto_eddsa "github.com/consensys/gnark-crypto/signature/eddsa"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	std_mimc "github.com/consensys/gnark/std/hash/mimc"
+	std_eddsa "github.com/consensys/gnark/std/signature/eddsa"
+
+	"gnark_on_icicle/benchmark"
+	"gnark_on_icicle/mimc"
+)
+
+/* Helper functions */
+
+// Get the twisted Edwards curve defined over the scalar field of the curve (e.g. BabyJubJub for bn254)
+func Get_edwards_curve(curve_id ecc.ID) (tedwards.ID, error) {
+	switch curve_id {
+	case ecc.BN254:
+		return tedwards.BN254, nil
+	case ecc.BLS12_377:
+		return tedwards.BLS12_377, nil
+	case ecc.BLS12_381:
+		return tedwards.BLS12_381, nil
+	case ecc.BW6_761:
+		return tedwards.BW6_761, nil
+	default:
+		return 0, fmt.Errorf("curve %s is not supported", curve_id.String())
+	}
+}
+
+// Signature_input holds a message, the public key of the signer and the signature in the gnark-crypto formats
+type Signature_input struct {
+	Msg       *big.Int
+	Pub_key   []byte
+	Signature []byte
+}
+
+func Gen_rand_inputs(n int, curve_id ecc.ID) ([]Signature_input, error) {
+	edwards_id, err := Get_edwards_curve(curve_id)
+	if err != nil {
+		return nil, err
+	}
+	h, err := mimc.Get_hash(curve_id)
+	if err != nil {
+		return nil, err
+	}
+	inputs := make([]Signature_input, n)
+
+	for i := 0; i < n; i++ {
+		// Generate a new key pair and a random message in the scalar field
+		priv_key, err := crypto_eddsa.New(edwards_id, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := rand.Int(rand.Reader, curve_id.ScalarField())
+		if err != nil {
+			return nil, err
+		}
+
+		// Sign the message with MiMC as the hash function, like in the circuit
+		hasher := h.New()
+		msg_data := make([]byte, hasher.BlockSize())
+		msg.FillBytes(msg_data)
+		signature, err := priv_key.Sign(msg_data, hasher)
+		if err != nil {
+			return nil, err
+		}
+
+		inputs[i] = Signature_input{Msg: msg, Pub_key: priv_key.Public().Bytes(), Signature: signature}
+	}
+
+	return inputs, nil
+}
+
+// Function to read file and extract the signatures
+// Each line contains the message, the compressed public key and the signature, all in hexadecimal
+func Parse_file(file_path string) ([]Signature_input, error) {
+	// Open the file
+	file, err := os.Open(file_path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var inputs []Signature_input
+
+	scanner := bufio.NewScanner(file)
+	line_num := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		line_num++
+		parts := strings.Split(line, " ")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid line format: %s", line)
+		}
+		// Decode the message, the public key and the signature from hexadecimal strings
+		msg, succ := new(big.Int).SetString(parts[0], 16)
+		if !succ {
+			return nil, fmt.Errorf("error decoding message at line %d: Failed to convert string to big.Int", line_num)
+		}
+		pub_key, err := hex.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("error decoding public key at line %d: %v", line_num, err)
+		}
+		signature, err := hex.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("error decoding signature at line %d: %v", line_num, err)
+		}
+		inputs = append(inputs, Signature_input{Msg: msg, Pub_key: pub_key, Signature: signature})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return inputs, nil
+}
+
+// EdDSACircuit defines the verification of an EdDSA signature with MiMC as the hash function
+// The twisted Edwards curve is defined over the scalar field of the proving curve, so the arithmetic is native.
+// The message and the public key are public, the signature is secret.
+type EdDSACircuit struct {
+	PublicKey std_eddsa.PublicKey `gnark:",public"`
+	Message   frontend.Variable   `gnark:",public"`
+	Signature std_eddsa.Signature
+
+	Edwards_id tedwards.ID `gnark:"-"`
+}
+
+// Define declares the circuit's constraints
+// Verify(PublicKey, Message, Signature) == true
+func (circuit *EdDSACircuit) Define(api frontend.API) error {
+	curve, err := twistededwards.NewEdCurve(api, circuit.Edwards_id)
+	if err != nil {
+		return err
+	}
+	h, err := std_mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	return std_eddsa.Verify(curve, circuit.Signature, circuit.Message, circuit.PublicKey, &h)
+}
+
+func assignment(edwards_id tedwards.ID, msg *big.Int, input Signature_input) (a *EdDSACircuit, err error) {
+	// Assign panics when the public key or the signature cannot be decoded, which can happen with inputs from a file
+	defer func() {
+		if r := recover(); r != nil {
+			a, err = nil, fmt.Errorf("invalid public key or signature: %v", r)
+		}
+	}()
+	a = &EdDSACircuit{Message: msg}
+	a.PublicKey.Assign(edwards_id, input.Pub_key)
+	a.Signature.Assign(edwards_id, input.Signature)
+	return a, nil
+}
+
+// New_circuit returns the circuit verifying one signature on the twisted Edwards curve of curve_id
+func New_circuit(curve_id ecc.ID) (*EdDSACircuit, error) {
+	edwards_id, err := Get_edwards_curve(curve_id)
+	if err != nil {
+		return nil, err
+	}
+	return &EdDSACircuit{Edwards_id: edwards_id}, nil
+}
+
+func Benchmark(cfg benchmark.Config, inputs []Signature_input) error {
+	if len(inputs) == 0 {
+		fmt.Println("No inputs were given. Please check your input!")
+		return nil
+	}
+	circuit, err := New_circuit(cfg.Curve_id)
+	if err != nil {
+		return err
+	}
+	edwards_id := circuit.Edwards_id
+
+	// Create the circuit assignments
+	// The invalid assignments use a wrong message so that the signature does not verify
+	assignments := make([]frontend.Circuit, len(inputs))
+	invalid_assignments := make([]frontend.Circuit, len(inputs))
+	for i, input := range inputs {
+		assignments[i], err = assignment(edwards_id, input.Msg, input)
+		if err != nil {
+			return err
+		}
+		invalid_assignments[i], err = assignment(edwards_id, new(big.Int).Add(input.Msg, big.NewInt(1)), input)
+		if err != nil {
+			return err
+		}
+	}
+
+	return benchmark.Run(cfg, "eddsa", circuit, assignments, invalid_assignments)
+}
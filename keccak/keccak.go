@@ -0,0 +1,177 @@
+package keccak
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/math/uints"
+	x_sha3 "golang.org/x/crypto/sha3"
+
+	"github.com/consensys/gnark/frontend"
+
+	"gnark_on_icicle/benchmark"
+)
+
+/* Helper functions */
+
+func convert_hash_bytes_to_gnarkU8(hash_byte_arr [32]byte) [32]uints.U8 {
+	var hash_gnarkU8_arr [32]uints.U8
+
+	for i, b := range hash_byte_arr {
+		hash_gnarkU8_arr[i] = uints.NewU8(uint8(b))
+	}
+
+	return hash_gnarkU8_arr
+}
+
+// Keccak256 computes the legacy Keccak-256 hash used by Ethereum (not the standardized SHA3-256)
+func Keccak256(data []byte) [32]byte {
+	var hash [32]byte
+	h := x_sha3.NewLegacyKeccak256()
+	h.Write(data)
+	h.Sum(hash[:0])
+	return hash
+}
+
+func Gen_rand_inputs(n int, preimage_size int) ([][32]byte, [][]byte, error) {
+	if preimage_size < 0 {
+		return nil, nil, fmt.Errorf("the size of the pre-image cannot be negative")
+	}
+	rand_hashes := make([][32]byte, n)
+	rand_preimages := make([][]byte, n)
+
+	for i := 0; i < n; i++ {
+		// Generate random bytes
+		rand_preimages[i] = make([]byte, preimage_size)
+		_, err := rand.Read(rand_preimages[i])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Calculate Keccak-256 hash
+		rand_hashes[i] = Keccak256(rand_preimages[i])
+	}
+
+	return rand_hashes, rand_preimages, nil
+}
+
+// Function to read file and extract hashes and preimages
+// All the pre-images must have the same size, which gives the size of the pre-image in the circuit
+func Parse_file(file_path string) ([][32]byte, [][]byte, error) {
+	// Open the file
+	file, err := os.Open(file_path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var hashes [][32]byte
+	var preimages [][]byte
+
+	scanner := bufio.NewScanner(file)
+	line_num := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		line_num++
+		parts := strings.Split(line, " ")
+		if len(parts) != 2 {
+			return nil, nil, fmt.Errorf("invalid line format: %s", line)
+		}
+		// Check if the hashes and the preimages are the correct length
+		if len(parts[0]) != 32*2 {
+			return nil, nil, fmt.Errorf("invaild hash not 32 bytes at line %d", line_num)
+		}
+		if len(preimages) > 0 && len(parts[1]) != len(preimages[0])*2 {
+			return nil, nil, fmt.Errorf("invaild preimage not %d bytes at line %d", len(preimages[0]), line_num)
+		}
+		// Decode the hashes and preimages from hexadecimal strings into byte arrays
+		hash_bytes, err := hex.DecodeString(parts[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decoding hash at line %d: %v", line_num, err)
+		}
+
+		preimage_bytes, err := hex.DecodeString(parts[1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decoding pre-image at line %d: %v", line_num, err)
+		}
+
+		hashes = append(hashes, [32]byte(hash_bytes))
+		preimages = append(preimages, preimage_bytes)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return hashes, preimages, nil
+}
+
+// Circuit defines a pre-image knowledge proof
+// Keccak256(secret PreImage) = public Hash
+type KeccakCircuit struct {
+	PreImage []uints.U8
+	Hash     [32]uints.U8 `gnark:",public"`
+}
+
+// Define declares the circuit's constraints
+// Hash = keccak256(PreImage)
+func (circuit *KeccakCircuit) Define(api frontend.API) error {
+	h, err := sha3.NewLegacyKeccak256(api)
+	if err != nil {
+		return err
+	}
+	uapi, err := uints.New[uints.U64](api)
+	if err != nil {
+		return err
+	}
+	h.Write(circuit.PreImage)
+	res := h.Sum()
+	if len(res) != 32 {
+		return fmt.Errorf("not 32 bytes")
+	}
+	for i := range circuit.Hash {
+		uapi.ByteAssertEq(circuit.Hash[i], res[i])
+	}
+	return nil
+}
+
+// New_circuit returns the circuit for pre-images of preimage_size bytes
+func New_circuit(preimage_size int) *KeccakCircuit {
+	return &KeccakCircuit{PreImage: make([]uints.U8, preimage_size)}
+}
+
+func Benchmark(cfg benchmark.Config, hashes [][32]byte, preimages [][]byte) error {
+
+	// Check if we have the same number of hashes and preimages
+	if len(hashes) != len(preimages) {
+		fmt.Println("The number of hashes and pre-images are not equal. Please check your input!")
+		return nil
+	}
+	if len(preimages) == 0 {
+		fmt.Println("No pre-images were given. Please check your input!")
+		return nil
+	}
+	preimage_size := len(preimages[0])
+
+	// Create the circuit assignments
+	// The invalid assignments use a wrong hash so that the constraints cannot be satisfied
+	assignments := make([]frontend.Circuit, len(hashes))
+	invalid_assignments := make([]frontend.Circuit, len(hashes))
+	for i := 0; i < len(hashes); i++ {
+		if len(preimages[i]) != preimage_size {
+			return fmt.Errorf("all the pre-images must have %d bytes", preimage_size)
+		}
+		assignments[i] = &KeccakCircuit{PreImage: uints.NewU8Array(preimages[i]), Hash: convert_hash_bytes_to_gnarkU8(hashes[i])}
+		wrong_hash := hashes[i]
+		wrong_hash[0] ^= 1
+		invalid_assignments[i] = &KeccakCircuit{PreImage: uints.NewU8Array(preimages[i]), Hash: convert_hash_bytes_to_gnarkU8(wrong_hash)}
+	}
+
+	cfg.Circuit_params = map[string]int{"Keccak preimage size": preimage_size}
+	return benchmark.Run(cfg, "keccak", New_circuit(preimage_size), assignments, invalid_assignments)
+}
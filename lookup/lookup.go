@@ -0,0 +1,308 @@
+package lookup
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/csv"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/lookup/logderivlookup"
+	"github.com/consensys/gnark/std/math/bits"
+
+	"gnark_on_icicle/benchmark"
+)
+
+// Maximum number of bits of the range checks
+const MAX_RANGE_BITS = 64
+
+// Maximum number of bits of the indices of the lookup table, the table has 2^bits entries
+// The naive lookup costs about 2^bits constraints per query.
+const MAX_TABLE_BITS = 16
+
+// Variants of the circuits, both are benchmarked one after the other
+// naive: bit decomposition of the values (like bits.ToBinary in exponentiate)
+// logderiv: gnark's log-derivative argument, which uses a commitment of the queried values
+var Variants = []string{"naive", "logderiv"}
+
+/* Helper functions */
+
+// Gen_rand_inputs generates n sets of count random values of nb_bits bits
+// The values are range checked by the rangecheck circuit and used as indices by the lookup circuit.
+func Gen_rand_inputs(n int, count int, nb_bits int) ([][]*big.Int, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("the number of checks must be at least 1")
+	}
+	if nb_bits < 1 || nb_bits > MAX_RANGE_BITS {
+		return nil, fmt.Errorf("the number of bits must be between 1 and %d", MAX_RANGE_BITS)
+	}
+	bound := new(big.Int).Lsh(big.NewInt(1), uint(nb_bits))
+	values := make([][]*big.Int, n)
+
+	for i := 0; i < n; i++ {
+		values[i] = make([]*big.Int, count)
+		for j := range values[i] {
+			v, err := rand.Int(rand.Reader, bound)
+			if err != nil {
+				return nil, err
+			}
+			values[i][j] = v
+		}
+	}
+
+	return values, nil
+}
+
+// Function to read file and extract the values
+// Each line contains the values of one proof in decimal, all the lines must have the same number of values
+func Parse_file(file_path string) ([][]*big.Int, error) {
+	// Open the file
+	file, err := os.Open(file_path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var values [][]*big.Int
+
+	scanner := bufio.NewScanner(file)
+	// The lines get long with many values
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line_num := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		line_num++
+		parts := strings.Split(line, " ")
+		// All the lines need to have the same number of values to use the same circuit
+		if len(values) > 0 && len(parts) != len(values[0]) {
+			return nil, fmt.Errorf("invalid line not %d values at line %d", len(values[0]), line_num)
+		}
+		line_values := make([]*big.Int, len(parts))
+		for j := range parts {
+			var succ bool
+			line_values[j], succ = new(big.Int).SetString(parts[j], 10)
+			if !succ {
+				return nil, fmt.Errorf("error decoding value %d at line %d: Failed to convert string to big.Int", j, line_num)
+			}
+		}
+		values = append(values, line_values)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// check_values checks that all the sets have the same number of values and that the values fit in nb_bits bits
+func check_values(values [][]*big.Int, nb_bits int) error {
+	if len(values) == 0 {
+		return fmt.Errorf("no values were given")
+	}
+	for i := range values {
+		if len(values[i]) != len(values[0]) {
+			return fmt.Errorf("all the inputs must have %d values", len(values[0]))
+		}
+		for _, v := range values[i] {
+			if v.Sign() < 0 || v.BitLen() > nb_bits {
+				return fmt.Errorf("the value %s of input %d does not fit in %d bits", v.String(), i, nb_bits)
+			}
+		}
+	}
+	return nil
+}
+
+// Table returns the entries of the lookup table with 2^bits entries
+// T[j] = j^3 + j + 5 like the cubic circuit, so that the entries are not just the indices
+func Table(nb_bits int) []*big.Int {
+	table := make([]*big.Int, 1<<nb_bits)
+	for j := range table {
+		x := big.NewInt(int64(j))
+		table[j] = new(big.Int).Add(new(big.Int).Exp(x, big.NewInt(3), nil), x)
+		table[j].Add(table[j], big.NewInt(5))
+	}
+	return table
+}
+
+// LookupCircuit defines count lookups in a constant table with 2^Bits entries
+// The indices are secret, the results are public
+type LookupCircuit struct {
+	Indices []frontend.Variable
+	Results []frontend.Variable `gnark:",public"`
+
+	Bits     int  `gnark:"-"`
+	Logderiv bool `gnark:"-"`
+}
+
+// Define declares the circuit's constraints
+// Results[i] == T[Indices[i]]
+func (circuit *LookupCircuit) Define(api frontend.API) error {
+	if len(circuit.Indices) != len(circuit.Results) {
+		return fmt.Errorf("%d indices and %d results", len(circuit.Indices), len(circuit.Results))
+	}
+	table := Table(circuit.Bits)
+
+	var results []frontend.Variable
+	if circuit.Logderiv {
+		// The table is committed with the queries and checked with a single log-derivative argument
+		// An index out of the table cannot be queried so the indices are range checked too
+		t := logderivlookup.New(api)
+		for _, entry := range table {
+			t.Insert(entry)
+		}
+		results = t.Lookup(circuit.Indices...)
+	} else {
+		// Decompose the index in bits and select the entry with a binary tree of Select
+		// The leaves of the tree are constants so the first level is free
+		results = make([]frontend.Variable, len(circuit.Indices))
+		for i, index := range circuit.Indices {
+			index_bits := bits.ToBinary(api, index, bits.WithNbDigits(circuit.Bits))
+			level := make([]frontend.Variable, len(table))
+			for j := range table {
+				level[j] = table[j]
+			}
+			for _, b := range index_bits {
+				next := make([]frontend.Variable, len(level)/2)
+				for j := range next {
+					next[j] = api.Select(b, level[2*j+1], level[2*j])
+				}
+				level = next
+			}
+			results[i] = level[0]
+		}
+	}
+
+	for i := range results {
+		api.AssertIsEqual(circuit.Results[i], results[i])
+	}
+	return nil
+}
+
+// Comparison of the variants
+type variant_stats struct {
+	name           string
+	nb_constraints int
+	nb_commitments int
+	setup          time.Duration
+	prove          time.Duration
+	verify         time.Duration
+}
+
+func average(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range durations {
+		sum += d
+	}
+	return sum / time.Duration(len(durations))
+}
+
+// compare runs the benchmark of each variant of the circuit and prints their statistics side by side
+// Each variant writes its own output folder, circuit(logderiv) returns the circuit of the variant. The logderiv variant
+// runs first: its commitment is the only part of the variants that an option can reject (see benchmark.Check_support),
+// so an unsupported option fails right after its compilation, before any variant is benchmarked. The comparison is
+// written in the output folder of each variant.
+func compare(cfg benchmark.Config, name string, circuit func(logderiv bool) frontend.Circuit, assignments []frontend.Circuit, invalid_assignments []frontend.Circuit) error {
+	stats := make([]variant_stats, len(Variants))
+	outp_folderpaths := make([]string, len(Variants))
+	for v := len(Variants) - 1; v >= 0; v-- {
+		variant := Variants[v]
+		fmt.Println("Benchmarking the", variant, "variant...")
+		res, err := benchmark.Run_with_result(cfg, name+"_"+variant, circuit(variant == "logderiv"), assignments, invalid_assignments)
+		if err != nil {
+			return fmt.Errorf("%s variant: %v", variant, err)
+		}
+		stats[v] = variant_stats{
+			name:           variant,
+			nb_constraints: res.Ccs.GetNbConstraints(),
+			nb_commitments: benchmark.Nb_commitments(res.Ccs),
+			setup:          res.Setup_time,
+			prove:          average(res.Prove_times),
+			verify:         average(res.Verify_times),
+		}
+		outp_folderpaths[v] = res.Outp_folderpath
+	}
+
+	fmt.Printf("Comparison of the %s variants on %s:\n", name, cfg.Curve_id.String())
+	fmt.Printf("%-10s %14s %12s %14s %14s %14s\n", "Variant", "Constraints", "Commitments", "Setup", "Avg prove", "Avg verify")
+	for _, s := range stats {
+		fmt.Printf("%-10s %14d %12d %14s %14s %14s\n", s.name, s.nb_constraints, s.nb_commitments, s.setup.Round(time.Millisecond), s.prove.Round(time.Millisecond), s.verify.Round(time.Millisecond))
+	}
+	for _, outp_folderpath := range outp_folderpaths {
+		if err := write_comparison(fmt.Sprintf("%s/%s_comparison.csv", outp_folderpath, name), stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write_comparison writes the statistics of the variants in a CSV file, the durations are in ms
+func write_comparison(filepath string, stats []variant_stats) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d.Microseconds())/1000.0, 'f', 3, 64)
+	}
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"Variant", "Constraints", "Commitments", "Setup", "Avg prove", "Avg verify"})
+	for _, s := range stats {
+		writer.Write([]string{s.name, strconv.Itoa(s.nb_constraints), strconv.Itoa(s.nb_commitments), ms(s.setup), ms(s.prove), ms(s.verify)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	fmt.Println("Comparison written in", filepath)
+	return nil
+}
+
+// New_lookup_circuit returns the circuit for count lookups in a table of 2^nb_bits entries
+func New_lookup_circuit(count int, nb_bits int, logderiv bool) *LookupCircuit {
+	return &LookupCircuit{Indices: make([]frontend.Variable, count), Results: make([]frontend.Variable, count), Bits: nb_bits, Logderiv: logderiv}
+}
+
+func Benchmark_lookup(cfg benchmark.Config, nb_bits int, indices [][]*big.Int) error {
+	if nb_bits < 1 || nb_bits > MAX_TABLE_BITS {
+		return fmt.Errorf("the number of bits of the table indices must be between 1 and %d", MAX_TABLE_BITS)
+	}
+	if err := check_values(indices, nb_bits); err != nil {
+		return err
+	}
+	count := len(indices[0])
+	table := Table(nb_bits)
+
+	// Create the circuit assignments
+	// The invalid assignments use a wrong first result so that the constraints cannot be satisfied
+	assignments := make([]frontend.Circuit, len(indices))
+	invalid_assignments := make([]frontend.Circuit, len(indices))
+	for i := range indices {
+		a := LookupCircuit{Indices: make([]frontend.Variable, count), Results: make([]frontend.Variable, count)}
+		wrong := LookupCircuit{Indices: make([]frontend.Variable, count), Results: make([]frontend.Variable, count)}
+		for j, index := range indices[i] {
+			a.Indices[j] = index
+			a.Results[j] = table[index.Int64()]
+			wrong.Indices[j] = index
+			wrong.Results[j] = table[index.Int64()]
+		}
+		wrong.Results[0] = new(big.Int).Add(table[indices[i][0].Int64()], big.NewInt(1))
+		assignments[i] = &a
+		invalid_assignments[i] = &wrong
+	}
+
+	circuit := func(logderiv bool) frontend.Circuit {
+		return New_lookup_circuit(count, nb_bits, logderiv)
+	}
+	cfg.Circuit_params = map[string]int{"Lookups": count, "Lookup table bits": nb_bits}
+	return compare(cfg, "lookup", circuit, assignments, invalid_assignments)
+}
@@ -0,0 +1,89 @@
+package lookup
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/rangecheck"
+
+	"gnark_on_icicle/benchmark"
+)
+
+// RangeCheckCircuit defines count range checks of Bits bits
+// The values are secret, their sum is public so that the proof is bound to the values
+type RangeCheckCircuit struct {
+	Values []frontend.Variable
+	Sum    frontend.Variable `gnark:",public"`
+
+	Bits     int  `gnark:"-"`
+	Logderiv bool `gnark:"-"`
+}
+
+// Define declares the circuit's constraints
+// 0 <= Values[i] < 2^Bits and Sum == Values[0] + ... + Values[count-1]
+func (circuit *RangeCheckCircuit) Define(api frontend.API) error {
+	if circuit.Logderiv {
+		// The values are decomposed in limbs which are checked against a table with a log-derivative argument
+		// The r1cs builder implements frontend.Committer so the checker uses the commitment
+		checker := rangecheck.New(api)
+		for _, v := range circuit.Values {
+			checker.Check(v, circuit.Bits)
+		}
+	} else {
+		// One boolean constraint per bit and one constraint for the recomposition
+		for _, v := range circuit.Values {
+			bits.ToBinary(api, v, bits.WithNbDigits(circuit.Bits))
+		}
+	}
+
+	sum := frontend.Variable(0)
+	for _, v := range circuit.Values {
+		sum = api.Add(sum, v)
+	}
+	api.AssertIsEqual(circuit.Sum, sum)
+	return nil
+}
+
+// New_rangecheck_circuit returns the circuit for count range checks of nb_bits bits
+func New_rangecheck_circuit(count int, nb_bits int, logderiv bool) *RangeCheckCircuit {
+	return &RangeCheckCircuit{Values: make([]frontend.Variable, count), Bits: nb_bits, Logderiv: logderiv}
+}
+
+func Benchmark_rangecheck(cfg benchmark.Config, nb_bits int, values [][]*big.Int) error {
+	if nb_bits < 1 || nb_bits > MAX_RANGE_BITS {
+		return fmt.Errorf("the number of bits must be between 1 and %d", MAX_RANGE_BITS)
+	}
+	if err := check_values(values, nb_bits); err != nil {
+		return err
+	}
+	count := len(values[0])
+
+	// Create the circuit assignments
+	// The invalid assignments replace the first value with 2^bits, which is out of the range, and update the sum
+	assignments := make([]frontend.Circuit, len(values))
+	invalid_assignments := make([]frontend.Circuit, len(values))
+	for i := range values {
+		a := RangeCheckCircuit{Values: make([]frontend.Variable, count)}
+		wrong := RangeCheckCircuit{Values: make([]frontend.Variable, count)}
+		sum := big.NewInt(0)
+		for j, v := range values[i] {
+			a.Values[j] = v
+			wrong.Values[j] = v
+			sum.Add(sum, v)
+		}
+		a.Sum = sum
+		out_of_range := new(big.Int).Lsh(big.NewInt(1), uint(nb_bits))
+		wrong.Values[0] = out_of_range
+		wrong.Sum = new(big.Int).Add(new(big.Int).Sub(sum, values[i][0]), out_of_range)
+		assignments[i] = &a
+		invalid_assignments[i] = &wrong
+	}
+
+	circuit := func(logderiv bool) frontend.Circuit {
+		return New_rangecheck_circuit(count, nb_bits, logderiv)
+	}
+	cfg.Circuit_params = map[string]int{"Range checks": count, "Range check bits": nb_bits}
+	return compare(cfg, "rangecheck", circuit, assignments, invalid_assignments)
+}
@@ -3,17 +3,80 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"strings"
 
+	"gnark_on_icicle/benchmark"
+	"gnark_on_icicle/bls"
+	"gnark_on_icicle/circom"
+	"gnark_on_icicle/constants"
 	"gnark_on_icicle/cubic"
+	"gnark_on_icicle/ecdsa"
+	"gnark_on_icicle/eddsa"
 	"gnark_on_icicle/exponentiate"
+	"gnark_on_icicle/keccak"
+	"gnark_on_icicle/lookup"
+	"gnark_on_icicle/merkle"
+	"gnark_on_icicle/mimc"
+	"gnark_on_icicle/poseidon"
+	"gnark_on_icicle/recursion"
 	"gnark_on_icicle/sha256"
+	"gnark_on_icicle/synthetic"
 
 	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
 )
 
 const MAX_INPUTS = 1000
 
-func benchmark_from_file(circuit string, curve_id ecc.ID, GPU_Acc bool, file_path string) {
+// circuit_params holds the runtime parameters of the circuits that have them
+type circuit_params struct {
+	// cubic, exponentiate
+	Batch int
+	// poseidon
+	Width       int
+	Nb_elements int
+	// mimc, sha256_chain
+	Chain_length int
+	// merkle
+	Hash  string
+	Depth int
+	// sha256, keccak
+	Preimage_size int
+	// sha256
+	Max_preimage_size int
+	// recursion
+	Inner string
+	// bls
+	Signers int
+	// rangecheck, lookup
+	Checks int
+	Bits   int
+	// synthetic
+	Synthetic synthetic.Params
+}
+
+// add_circuit_flags defines the flags of the runtime parameters of the circuits
+func add_circuit_flags(flags *flag.FlagSet, params *circuit_params) {
+	flags.IntVar(&params.Batch, "batch", 1, "Number of instances packed in one circuit (cubic, exponentiate, recursion with cubic)")
+	flags.IntVar(&params.Width, "width", 3, "Width of the Poseidon permutation (poseidon, merkle)")
+	flags.IntVar(&params.Nb_elements, "nb_elements", 2, "Number of field elements in the random pre-images (poseidon)")
+	flags.IntVar(&params.Chain_length, "chain_length", 10, "Number of iterations of the hash chain (mimc, sha256_chain)")
+	flags.StringVar(&params.Hash, "hash", "mimc", "Hash used for the nodes of the tree: mimc, poseidon or sha256 (merkle)")
+	flags.IntVar(&params.Depth, "depth", 10, "Depth of the random tree (merkle)")
+	flags.IntVar(&params.Preimage_size, "preimage_size", constants.PREIMAGE_SIZE, "Size in bytes of the random pre-images (sha256, keccak, recursion with sha256)")
+	flags.IntVar(&params.Max_preimage_size, "max_preimage_size", 0, "Maximum size in bytes of the pre-images accepted by the circuit, 0 for the longest pre-image (sha256, recursion with sha256)")
+	flags.StringVar(&params.Inner, "inner", "cubic", "Inner circuit proven on bls12_377 and verified on bw6_761: cubic or sha256 (recursion)")
+	flags.IntVar(&params.Signers, "signers", 1, "Number of signers of the aggregated BLS12-381 signature (bls)")
+	flags.IntVar(&params.Checks, "checks", 1000, "Number of range checks or lookups per proof (rangecheck, lookup)")
+	flags.IntVar(&params.Bits, "bits", 8, "Number of bits of the range checks or of the lookup table indices (rangecheck, lookup)")
+	flags.IntVar(&params.Synthetic.Mul, "mul_constraints", 1<<10, "Number of multiplication constraints (synthetic)")
+	flags.IntVar(&params.Synthetic.Add, "add_constraints", 0, "Number of addition constraints (synthetic)")
+	flags.IntVar(&params.Synthetic.Public, "public", 1, "Number of public inputs including the output (synthetic, given by the lines of the file with -file_path)")
+	flags.IntVar(&params.Synthetic.Fan_out, "fan_out", 1, "Number of wires in each operand of a constraint (synthetic)")
+}
+
+func benchmark_from_file(circuit string, cfg benchmark.Config, params circuit_params, file_path string) {
 	switch circuit {
 	case "cubic":
 		x, y, err := cubic.Parse_file(file_path)
@@ -21,7 +84,10 @@ func benchmark_from_file(circuit string, curve_id ecc.ID, GPU_Acc bool, file_pat
 			fmt.Println("Error parsing file: ", err)
 			return
 		}
-		cubic.Benchmark(curve_id, GPU_Acc, x, y)
+		if err := cubic.Benchmark(cfg, x, y); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
 		break
 	case "exponentiate":
 		x, y, e, err := exponentiate.Parse_file(file_path)
@@ -29,7 +95,10 @@ func benchmark_from_file(circuit string, curve_id ecc.ID, GPU_Acc bool, file_pat
 			fmt.Println("Error parsing file: ", err)
 			return
 		}
-		exponentiate.Benchmark(curve_id, GPU_Acc, x, y, e)
+		if err := exponentiate.Benchmark(cfg, x, y, e); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
 		break
 	case "sha256":
 		hashes, preimages, err := sha256.Parse_file(file_path)
@@ -37,8 +106,149 @@ func benchmark_from_file(circuit string, curve_id ecc.ID, GPU_Acc bool, file_pat
 			fmt.Println("Error parsing file: ", err)
 			return
 		}
-		sha256.Benchmark(curve_id, GPU_Acc, hashes, preimages)
+		if err := sha256.Benchmark(cfg, params.Max_preimage_size, hashes, preimages); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
 		break
+	case "poseidon":
+		poseidon_params, err := poseidon.New_params(params.Width, cfg.Curve_id.ScalarField())
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		hashes, preimages, err := poseidon.Parse_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		if err := poseidon.Benchmark(cfg, poseidon_params, hashes, preimages); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "mimc":
+		digests, seeds, err := mimc.Parse_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		if err := mimc.Benchmark(cfg, params.Chain_length, digests, seeds); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "merkle":
+		hasher, err := merkle.New_hasher(params.Hash, cfg.Curve_id, params.Width)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		memberships, err := merkle.Parse_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		if err := merkle.Benchmark(cfg, hasher, memberships); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "keccak":
+		hashes, preimages, err := keccak.Parse_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		if err := keccak.Benchmark(cfg, hashes, preimages); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "ecdsa":
+		signatures, err := ecdsa.Parse_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		if err := ecdsa.Benchmark(cfg, signatures); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "eddsa":
+		signatures, err := eddsa.Parse_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		if err := eddsa.Benchmark(cfg, signatures); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "bls":
+		signatures, err := bls.Parse_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		if err := bls.Benchmark(cfg, signatures); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "rangecheck":
+		values, err := lookup.Parse_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		if err := lookup.Benchmark_rangecheck(cfg, params.Bits, values); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "lookup":
+		values, err := lookup.Parse_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		if err := lookup.Benchmark_lookup(cfg, params.Bits, values); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "synthetic":
+		secrets, inputs, outputs, err := synthetic.Parse_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		// The number of public inputs is given by the lines of the file
+		if len(inputs) > 0 {
+			params.Synthetic.Public = len(inputs[0]) + 1
+		}
+		if err := synthetic.Benchmark(cfg, params.Synthetic, secrets, inputs, outputs); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "sha256_chain":
+		digests, seeds, err := sha256.Parse_chain_file(file_path)
+		if err != nil {
+			fmt.Println("Error parsing file: ", err)
+			return
+		}
+		if err := sha256.Benchmark_chain(cfg, params.Chain_length, digests, seeds); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "recursion":
+		fmt.Println("The recursion benchmark only runs on random inputs of the inner circuit. Please use -n instead of -file_path")
+		return
 	default:
 		fmt.Println("Circuit ", circuit, " unknown. The program will benchmark the sha256 circuit...")
 		hashes, preimages, err := sha256.Parse_file(file_path)
@@ -46,57 +256,466 @@ func benchmark_from_file(circuit string, curve_id ecc.ID, GPU_Acc bool, file_pat
 			fmt.Println("Error generating random inputs: ", err)
 			return
 		}
-		sha256.Benchmark(curve_id, GPU_Acc, hashes, preimages)
+		if err := sha256.Benchmark(cfg, params.Max_preimage_size, hashes, preimages); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
 		break
 	}
 	fmt.Println("Benchmark ran successfully. Exiting...")
 }
-func benchmark_rand_vals(circuit string, curve_id ecc.ID, GPU_Acc bool, n int) {
+func benchmark_rand_vals(circuit string, cfg benchmark.Config, params circuit_params, n int) {
 	switch circuit {
 	case "cubic":
-		x, y, err := cubic.Gen_rand_inputs(n)
+		x, y, err := cubic.Gen_rand_inputs(n, params.Batch)
 		if err != nil {
 			fmt.Println("Error : ", err)
 			return
 		}
-		cubic.Benchmark(curve_id, GPU_Acc, x, y)
+		if err := cubic.Benchmark(cfg, x, y); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
 		break
 	case "exponentiate":
-		x, y, e, err := exponentiate.Gen_rand_inputs(n)
+		x, y, e, err := exponentiate.Gen_rand_inputs(n, params.Batch)
 		if err != nil {
 			fmt.Println("Error : ", err)
 			return
 		}
-		exponentiate.Benchmark(curve_id, GPU_Acc, x, y, e)
+		if err := exponentiate.Benchmark(cfg, x, y, e); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
 		break
 	case "sha256":
-		hashes, preimages, err := sha256.Gen_rand_inputs(n)
+		hashes, preimages, err := sha256.Gen_rand_inputs(n, params.Preimage_size)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := sha256.Benchmark(cfg, params.Max_preimage_size, hashes, preimages); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "poseidon":
+		poseidon_params, err := poseidon.New_params(params.Width, cfg.Curve_id.ScalarField())
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		hashes, preimages, err := poseidon.Gen_rand_inputs(n, poseidon_params, params.Nb_elements)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := poseidon.Benchmark(cfg, poseidon_params, hashes, preimages); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "mimc":
+		digests, seeds, err := mimc.Gen_rand_inputs(n, cfg.Curve_id, params.Chain_length)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := mimc.Benchmark(cfg, params.Chain_length, digests, seeds); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "merkle":
+		hasher, err := merkle.New_hasher(params.Hash, cfg.Curve_id, params.Width)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		memberships, err := merkle.Gen_rand_inputs(n, hasher, params.Depth)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := merkle.Benchmark(cfg, hasher, memberships); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "keccak":
+		hashes, preimages, err := keccak.Gen_rand_inputs(n, params.Preimage_size)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := keccak.Benchmark(cfg, hashes, preimages); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "ecdsa":
+		signatures, err := ecdsa.Gen_rand_inputs(n)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := ecdsa.Benchmark(cfg, signatures); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "eddsa":
+		signatures, err := eddsa.Gen_rand_inputs(n, cfg.Curve_id)
 		if err != nil {
 			fmt.Println("Error : ", err)
 			return
 		}
-		sha256.Benchmark(curve_id, GPU_Acc, hashes, preimages)
+		if err := eddsa.Benchmark(cfg, signatures); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "bls":
+		signatures, err := bls.Gen_rand_inputs(n, params.Signers)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := bls.Benchmark(cfg, signatures); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "rangecheck":
+		values, err := lookup.Gen_rand_inputs(n, params.Checks, params.Bits)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := lookup.Benchmark_rangecheck(cfg, params.Bits, values); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "lookup":
+		values, err := lookup.Gen_rand_inputs(n, params.Checks, params.Bits)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := lookup.Benchmark_lookup(cfg, params.Bits, values); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "synthetic":
+		secrets, inputs, outputs, err := synthetic.Gen_rand_inputs(n, params.Synthetic, cfg.Curve_id.ScalarField())
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := synthetic.Benchmark(cfg, params.Synthetic, secrets, inputs, outputs); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "sha256_chain":
+		digests, seeds, err := sha256.Gen_rand_chain_inputs(n, params.Chain_length)
+		if err != nil {
+			fmt.Println("Error : ", err)
+			return
+		}
+		if err := sha256.Benchmark_chain(cfg, params.Chain_length, digests, seeds); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		break
+	case "recursion":
+		if err := recursion.Benchmark(cfg, recursion.Inner_params{Circuit: params.Inner, Batch: params.Batch,
+			Preimage_size: params.Preimage_size, Max_preimage_size: params.Max_preimage_size}, n); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
 		break
 	default:
 		fmt.Println("Circuit ", circuit, " unknown. The program will benchmark the sha256 circuit...")
-		hashes, preimages, err := sha256.Gen_rand_inputs(n)
+		hashes, preimages, err := sha256.Gen_rand_inputs(n, params.Preimage_size)
 		if err != nil {
 			fmt.Println("Error generating random inputs: ", err)
 			return
 		}
-		sha256.Benchmark(curve_id, GPU_Acc, hashes, preimages)
+		if err := sha256.Benchmark(cfg, params.Max_preimage_size, hashes, preimages); err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
 		break
 	}
 	fmt.Println("Benchmark ran successfully. Exiting...")
 }
 
+// parse_curve returns the curve of the -curve flag
+// An unknown curve is an error, falling back to another curve would make the artifacts of the curve fail to load
+func parse_curve(curve string) (ecc.ID, error) {
+	switch curve {
+	case "bn254":
+		return ecc.BN254, nil
+	case "bls12_377":
+		return ecc.BLS12_377, nil
+	case "bls12_381":
+		return ecc.BLS12_381, nil
+	case "bw6_761":
+		return ecc.BW6_761, nil
+	}
+	return ecc.UNKNOWN, fmt.Errorf("curve %s unknown, the curve must be bn254, bls12_377, bls12_381 or bw6_761", curve)
+}
+
+// verify_command verifies a proof saved with -save_proofs (groth16) or serialized by gnark (groth16 or PLONK)
+// Usage: main.go verify -curve bn254 -backend groth16 -vk verifying_key.bin -proof proof_0.bin -public_witness public_witness_0.bin -reps 10
+func verify_command(args []string) {
+	var curve string
+	var backend_name string
+	var vk_path string
+	var proof_path string
+	var public_witness_path string
+	var reps int
+
+	fmt.Println("Parsing arguments...")
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	flags.StringVar(&curve, "curve", "bn254", "Specify the curve")
+	flags.StringVar(&backend_name, "backend", "groth16", "Backend of the verifying key and of the proof (groth16 or plonk)")
+	flags.StringVar(&vk_path, "vk", "", "Path to the verifying key")
+	flags.StringVar(&proof_path, "proof", "", "Path to the proof")
+	flags.StringVar(&public_witness_path, "public_witness", "", "Path to the public witness")
+	flags.IntVar(&reps, "reps", 10, "Number of times the proof is verified")
+
+	flags.Parse(args)
+	if vk_path == "" || proof_path == "" || public_witness_path == "" {
+		fmt.Println("The paths to the verifying key, the proof and the public witness are required")
+		return
+	}
+	fmt.Println("Verification parameters: ")
+	fmt.Println("\t-curve:", curve)
+	fmt.Println("\t-backend:", backend_name)
+	fmt.Println("\t-repetitions:", reps)
+	curve_id, err := parse_curve(curve)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := benchmark.Verify_from_files(curve_id, backend_name, vk_path, proof_path, public_witness_path, reps); err != nil {
+		fmt.Println("Error verifying proof: ", err)
+		return
+	}
+	fmt.Println("Verification ran successfully. Exiting...")
+}
+
+// Circuits compiled by the compile command when -circuits is all
+// The outer circuit of recursion needs the verifying key of the inner circuit, which needs a setup.
+var COMPILE_CIRCUITS = []string{"cubic", "exponentiate", "sha256", "sha256_chain", "keccak", "poseidon", "mimc", "merkle",
+	"ecdsa", "eddsa", "bls", "rangecheck", "lookup", "synthetic"}
+
+// new_circuits returns the names and the constructors of the circuits benchmarked by -circuit, without assignments
+// The range checks and the lookups give one circuit per variant like their benchmarks.
+func new_circuits(circuit string, curve_id ecc.ID, params circuit_params) ([]string, []func() frontend.Circuit, error) {
+	one := func(name string, new_circuit func() frontend.Circuit) ([]string, []func() frontend.Circuit, error) {
+		return []string{name}, []func() frontend.Circuit{new_circuit}, nil
+	}
+	switch circuit {
+	case "cubic":
+		return one("cubic", func() frontend.Circuit { return cubic.New_circuit(params.Batch) })
+	case "exponentiate":
+		return one("exponentiate", func() frontend.Circuit { return exponentiate.New_circuit(params.Batch) })
+	case "sha256":
+		max_size := params.Max_preimage_size
+		if max_size == 0 {
+			max_size = params.Preimage_size
+		}
+		return one("sha256", func() frontend.Circuit { return sha256.New_circuit(max_size) })
+	case "sha256_chain":
+		return one("sha256_chain", func() frontend.Circuit { return sha256.New_chain_circuit(params.Chain_length) })
+	case "keccak":
+		return one("keccak", func() frontend.Circuit { return keccak.New_circuit(params.Preimage_size) })
+	case "poseidon":
+		poseidon_params, err := poseidon.New_params(params.Width, curve_id.ScalarField())
+		if err != nil {
+			return nil, nil, err
+		}
+		return one("poseidon", func() frontend.Circuit { return poseidon.New_circuit(poseidon_params, params.Nb_elements) })
+	case "mimc":
+		return one("mimc", func() frontend.Circuit { return mimc.New_circuit(params.Chain_length) })
+	case "merkle":
+		hasher, err := merkle.New_hasher(params.Hash, curve_id, params.Width)
+		if err != nil {
+			return nil, nil, err
+		}
+		return one("merkle_"+hasher.Name, func() frontend.Circuit { return merkle.New_circuit(hasher, params.Depth) })
+	case "ecdsa":
+		return one("ecdsa", func() frontend.Circuit { return ecdsa.New_circuit() })
+	case "eddsa":
+		if _, err := eddsa.New_circuit(curve_id); err != nil {
+			return nil, nil, err
+		}
+		return one("eddsa", func() frontend.Circuit {
+			c, _ := eddsa.New_circuit(curve_id)
+			return c
+		})
+	case "bls":
+		return one("bls", func() frontend.Circuit { return bls.New_circuit(params.Signers) })
+	case "rangecheck", "lookup":
+		names := make([]string, len(lookup.Variants))
+		constructors := make([]func() frontend.Circuit, len(lookup.Variants))
+		for v, variant := range lookup.Variants {
+			logderiv := variant == "logderiv"
+			names[v] = circuit + "_" + variant
+			if circuit == "rangecheck" {
+				constructors[v] = func() frontend.Circuit { return lookup.New_rangecheck_circuit(params.Checks, params.Bits, logderiv) }
+			} else {
+				constructors[v] = func() frontend.Circuit { return lookup.New_lookup_circuit(params.Checks, params.Bits, logderiv) }
+			}
+		}
+		return names, constructors, nil
+	case "synthetic":
+		if _, err := synthetic.New_circuit(params.Synthetic); err != nil {
+			return nil, nil, err
+		}
+		return one("synthetic", func() frontend.Circuit {
+			c, _ := synthetic.New_circuit(params.Synthetic)
+			return c
+		})
+	case "recursion":
+		return nil, nil, fmt.Errorf("the outer circuit needs the verifying key of the inner circuit, which needs a setup")
+	}
+	return nil, nil, fmt.Errorf("circuit %s unknown", circuit)
+}
+
+// compile_command compiles circuits with the R1CS (groth16) and SCS (PLONK) frontends on several curves and
+// compares the compile time, the memory allocated and the number of constraints, without setup or proving
+// Usage: main.go compile -circuits cubic,sha256 -curves bn254,bls12_377 -batch 10
+func compile_command(args []string) {
+	var circuits string
+	var curves string
+	var params circuit_params
+
+	fmt.Println("Parsing arguments...")
+	flags := flag.NewFlagSet("compile", flag.ExitOnError)
+	flags.StringVar(&circuits, "circuits", "all", "Comma separated circuits to compile, all for every circuit except recursion")
+	flags.StringVar(&curves, "curves", "bn254,bls12_377,bls12_381,bw6_761", "Comma separated curves")
+	add_circuit_flags(flags, &params)
+
+	flags.Parse(args)
+	circuit_list := COMPILE_CIRCUITS
+	if circuits != "all" {
+		circuit_list = strings.Split(circuits, ",")
+	}
+	fmt.Println("Compilation parameters: ")
+	fmt.Println("	-circuits:", strings.Join(circuit_list, ","))
+	fmt.Println("	-curves:", curves)
+
+	var comparisons []benchmark.Frontend_Comparison
+	for _, curve := range strings.Split(curves, ",") {
+		curve_id, err := parse_curve(curve)
+		if err != nil {
+			fmt.Println("Skipping the curve:", err)
+			continue
+		}
+		for _, circuit := range circuit_list {
+			names, constructors, err := new_circuits(circuit, curve_id, params)
+			if err != nil {
+				fmt.Printf("Skipping %s on %s: %v\n", circuit, curve_id.String(), err)
+				continue
+			}
+			for i := range names {
+				comparisons = append(comparisons, benchmark.Compare_frontends(curve_id, names[i], constructors[i]))
+			}
+		}
+	}
+	if len(comparisons) == 0 {
+		fmt.Println("No circuit was compiled. Please check the circuits and the curves!")
+		return
+	}
+	if err := benchmark.Write_frontend_comparison(comparisons); err != nil {
+		fmt.Println("Error writing the comparison: ", err)
+		return
+	}
+	fmt.Println("Compilation ran successfully. Exiting...")
+}
+
+// micro_command benchmarks the primitives of gnark-crypto (MSM, FFT, pairing) at the sizes of the groth16 proofs of
+// a circuit on several curves, each circuit and curve is written in its own output folder
+// Usage: main.go micro -circuit sha256 -curves bn254,bls12_377 -reps 5
+func micro_command(args []string) {
+	var circuit string
+	var curves string
+	var GPU_Acc bool
+	var reps int
+	var params circuit_params
+
+	fmt.Println("Parsing arguments...")
+	flags := flag.NewFlagSet("micro", flag.ExitOnError)
+	flags.StringVar(&circuit, "circuit", "sha256", "Circuit giving the sizes of the primitives")
+	flags.StringVar(&curves, "curves", "bn254,bls12_377,bls12_381,bw6_761", "Comma separated curves")
+	flags.BoolVar(&GPU_Acc, "GPU_Acc", false, "Also benchmark the primitives of icicle (bn254 only)")
+	flags.IntVar(&reps, "reps", 5, "Number of times each primitive is run")
+	add_circuit_flags(flags, &params)
+
+	flags.Parse(args)
+	fmt.Println("Micro-benchmark parameters: ")
+	fmt.Println("\t-circuit:", circuit)
+	fmt.Println("\t-curves:", curves)
+	fmt.Println("\t-GPU Acceleration: ", GPU_Acc)
+	fmt.Println("\t-repetitions:", reps)
+
+	for _, curve := range strings.Split(curves, ",") {
+		curve_id, err := parse_curve(curve)
+		if err != nil {
+			fmt.Println("Skipping the curve:", err)
+			continue
+		}
+		names, constructors, err := new_circuits(circuit, curve_id, params)
+		if err != nil {
+			fmt.Printf("Skipping %s on %s: %v\n", circuit, curve_id.String(), err)
+			continue
+		}
+		cfg := benchmark.Config{Curve_id: curve_id, GPU_Acc: GPU_Acc}
+		for i := range names {
+			if err := benchmark.Run_micro(cfg, names[i], constructors[i](), reps); err != nil {
+				fmt.Println("Error running micro-benchmark: ", err)
+				return
+			}
+		}
+	}
+	fmt.Println("Micro-benchmark ran successfully. Exiting...")
+}
+
 func main() {
+	// Run the sub-commands
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		verify_command(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "compile" {
+		compile_command(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "micro" {
+		micro_command(os.Args[2:])
+		return
+	}
 	// Parse arguments
 	var curve string
 	var circuit string
 	var GPU_Acc bool
 	var n int
 	var file_path string
+	var negative bool
+	var save_proofs bool
+	var solidity bool
+	var profile bool
+	var mpc int
+	var batch_verify bool
+	var ccs_path string
+	var witness_paths string
+	var params circuit_params
 
 	fmt.Println("Parsing arguments...")
 	flag.StringVar(&curve, "curve", "bn254", "Specify the curve")
@@ -104,35 +723,59 @@ func main() {
 	flag.BoolVar(&GPU_Acc, "GPU_Acc", false, "Enable GPU acceleration")
 	flag.IntVar(&n, "n", 0, "Number of random inputs to run the benchmark on")
 	flag.StringVar(&file_path, "file_path", "", "Path to file containing pre-determined inputs seperated by a space")
+	flag.BoolVar(&negative, "negative", false, "Also benchmark unsatisfiable assignments and tampered proofs/public inputs")
+	flag.BoolVar(&save_proofs, "save_proofs", false, "Save the verifying key and the proof and public witness of each run")
+	add_circuit_flags(flag.CommandLine, &params)
+	flag.BoolVar(&solidity, "solidity", false, "Export the Solidity verifier and measure the gas used to verify each proof in an EVM (bn254 only)")
+	flag.IntVar(&mpc, "mpc", 0, "Number of contributions to phase 2 of the MPC setup that replaces the groth16 setup, 0 for the setup with toxic randomness (bn254 only)")
+	flag.BoolVar(&batch_verify, "batch_verify", false, "Also verify the proofs of all the runs with one randomized multi-pairing check and find the invalid ones if it fails")
+	flag.BoolVar(&profile, "profile", false, "Write a gnark profile attributing the constraints to the lines of the circuit in the output folder")
+	flag.StringVar(&ccs_path, "ccs", "", "Path to a R1CS or SCS serialized with gnark or to a .r1cs file of circom, benchmarked instead of -circuit")
+	flag.StringVar(&witness_paths, "witness", "", "Comma separated paths to the full witnesses of the -ccs constraint system (gnark binary format or .wtns of circom), one run per witness")
 
 	flag.Parse()
 	fmt.Println("Benchmark parameters: ")
 	fmt.Println("\t-curve:", curve)
 	fmt.Println("\t-circuit:", circuit)
 	fmt.Println("\t-GPU Acceleration: ", GPU_Acc)
+	fmt.Println("\t-Negative tests: ", negative)
+	fmt.Println("\t-Save proofs: ", save_proofs)
+	fmt.Println("\t-Solidity verifier: ", solidity)
+	fmt.Println("\t-Constraint profile: ", profile)
+	fmt.Println("\t-MPC contributions: ", mpc)
+	fmt.Println("\t-Batch verification: ", batch_verify)
 	// Set the scalar field depending on the choice of the curve
-	var curve_id ecc.ID
-	switch curve {
-	case "bn254":
-		curve_id = ecc.BN254
-		break
-	case "bls12_377":
-		curve_id = ecc.BLS12_377
-		break
-	case "bls12_381":
-		curve_id = ecc.BLS12_381
-		break
-	case "bw6_761":
-		curve_id = ecc.BW6_761
-		break
-	default:
-		fmt.Println("Curve", curve, "unknown. The benchmark will use the bn254 curve...")
-		curve_id = ecc.BN254
-		break
+	curve_id, err := parse_curve(curve)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	cfg := benchmark.Config{Curve_id: curve_id, GPU_Acc: GPU_Acc, Negative: negative, Save_proofs: save_proofs, Solidity: solidity, Profile: profile,
+		MPC_contributions: mpc, Batch_verify: batch_verify}
+	// Benchmark an external constraint system
+	if ccs_path != "" {
+		if witness_paths == "" {
+			fmt.Println("The -ccs mode needs the path to at least one witness with -witness")
+			return
+		}
+		fmt.Println("\t-ccs:", ccs_path)
+		// The .r1cs files of circom are converted, the other files are read as gnark constraint systems
+		var err error
+		if circom.Is_r1cs_file(ccs_path) {
+			err = circom.Benchmark(cfg, ccs_path, strings.Split(witness_paths, ","))
+		} else {
+			err = benchmark.Run_ccs(cfg, ccs_path, strings.Split(witness_paths, ","))
+		}
+		if err != nil {
+			fmt.Println("Error running benchmark: ", err)
+			return
+		}
+		fmt.Println("Benchmark ran successfully. Exiting...")
+		return
 	}
 	// Get the inputs for the circuit
 	if file_path != "" {
-		benchmark_from_file(circuit, curve_id, GPU_Acc, file_path)
+		benchmark_from_file(circuit, cfg, params, file_path)
 		return
 	} else if n != 0 {
 		if n < 0 {
@@ -143,10 +786,10 @@ func main() {
 			fmt.Printf("The maximum number of inputs is %d. Pleas a give a smaller number for n\n", MAX_INPUTS)
 			return
 		}
-		benchmark_rand_vals(circuit, curve_id, GPU_Acc, n)
+		benchmark_rand_vals(circuit, cfg, params, n)
 	} else {
 		fmt.Println("No inputs were detected, the program will be running with 10 random inputs...")
-		benchmark_rand_vals(circuit, curve_id, GPU_Acc, 10)
+		benchmark_rand_vals(circuit, cfg, params, 10)
 		return
 	}
 
@@ -0,0 +1,153 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	std_mimc "github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/hash/sha2"
+	"github.com/consensys/gnark/std/math/uints"
+
+	"gnark_on_icicle/mimc"
+	"gnark_on_icicle/poseidon"
+)
+
+// Hasher hashes the nodes of the tree natively and in the circuit
+// The nodes are always stored as big integers. For MiMC and Poseidon a node is a field element and
+// takes a single variable in the circuit, for SHA-256 a node is the 32-byte digest read in big-endian
+// and takes one variable per byte in the circuit.
+type Hasher struct {
+	Name     string
+	Curve_id ecc.ID
+	// poseidon
+	Poseidon_params *poseidon.Params
+}
+
+func New_hasher(name string, curve_id ecc.ID, poseidon_width int) (*Hasher, error) {
+	h := &Hasher{Name: name, Curve_id: curve_id}
+	switch name {
+	case "mimc", "sha256":
+	case "poseidon":
+		params, err := poseidon.New_params(poseidon_width, curve_id.ScalarField())
+		if err != nil {
+			return nil, err
+		}
+		h.Poseidon_params = params
+	default:
+		return nil, fmt.Errorf("hash %s unknown, the supported hashes are mimc, poseidon and sha256", name)
+	}
+	return h, nil
+}
+
+// Node_size returns the number of circuit variables of a node
+func (h *Hasher) Node_size() int {
+	if h.Name == "sha256" {
+		return 32
+	}
+	return 1
+}
+
+// Rand_leaf returns a random leaf value
+func (h *Hasher) Rand_leaf() (*big.Int, error) {
+	if h.Name == "sha256" {
+		return rand_int(new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return rand_int(h.Curve_id.ScalarField())
+}
+
+// Hash computes the hash of the nodes natively
+func (h *Hasher) Hash(nodes ...*big.Int) (*big.Int, error) {
+	switch h.Name {
+	case "mimc":
+		mimc_hash, err := mimc.Get_hash(h.Curve_id)
+		if err != nil {
+			return nil, err
+		}
+		return mimc.Hash_elements(mimc_hash, nodes...), nil
+	case "poseidon":
+		return h.Poseidon_params.Hash(nodes), nil
+	case "sha256":
+		data := make([]byte, 32*len(nodes))
+		for i, node := range nodes {
+			node.FillBytes(data[32*i : 32*(i+1)])
+		}
+		digest := sha256.Sum256(data)
+		return new(big.Int).SetBytes(digest[:]), nil
+	default:
+		return nil, fmt.Errorf("hash %s unknown", h.Name)
+	}
+}
+
+// To_variables splits a node into the variables of the circuit
+func (h *Hasher) To_variables(node *big.Int) []frontend.Variable {
+	if h.Name != "sha256" {
+		return []frontend.Variable{node}
+	}
+	var digest [32]byte
+	node.FillBytes(digest[:])
+	vars := make([]frontend.Variable, 32)
+	for i, b := range digest {
+		vars[i] = b
+	}
+	return vars
+}
+
+// Hash_circuit computes the hash of the nodes in the circuit
+func (h *Hasher) Hash_circuit(api frontend.API, nodes ...[]frontend.Variable) ([]frontend.Variable, error) {
+	switch h.Name {
+	case "mimc":
+		hasher, err := std_mimc.NewMiMC(api)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			hasher.Write(node...)
+		}
+		return []frontend.Variable{hasher.Sum()}, nil
+	case "poseidon":
+		var elements []frontend.Variable
+		for _, node := range nodes {
+			elements = append(elements, node...)
+		}
+		return []frontend.Variable{poseidon.Hash_circuit(api, h.Poseidon_params, elements)}, nil
+	case "sha256":
+		uapi, err := uints.New[uints.U32](api)
+		if err != nil {
+			return nil, err
+		}
+		hasher, err := sha2.New(api)
+		if err != nil {
+			return nil, err
+		}
+		// The bytes are range checked before being hashed since they come from the witness or from a Select
+		for _, node := range nodes {
+			data := make([]uints.U8, len(node))
+			for i := range node {
+				data[i] = uapi.ByteValueOf(node[i])
+			}
+			hasher.Write(data)
+		}
+		digest := hasher.Sum()
+		res := make([]frontend.Variable, len(digest))
+		for i := range digest {
+			res[i] = digest[i].Val
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("hash %s unknown", h.Name)
+	}
+}
+
+// Circuit_params returns the parameters of the hash to record in the benchmark parameters
+func (h *Hasher) Circuit_params() map[string]int {
+	params := map[string]int{}
+	if h.Poseidon_params != nil {
+		params["Poseidon width"] = h.Poseidon_params.Width
+		params["Poseidon alpha"] = h.Poseidon_params.Alpha
+		params["Poseidon partial rounds"] = h.Poseidon_params.Partial_rounds
+	}
+	return params
+}
@@ -0,0 +1,247 @@
+package merkle
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/consensys/gnark/frontend"
+
+	"gnark_on_icicle/benchmark"
+)
+
+// Maximum depth of the random trees, the whole tree is kept in memory while generating the inputs
+const MAX_DEPTH = 24
+
+/* Helper functions */
+
+func rand_int(max *big.Int) (*big.Int, error) {
+	return rand.Int(rand.Reader, max)
+}
+
+// Membership holds the inputs of one membership proof
+type Membership struct {
+	Root  *big.Int
+	Leaf  *big.Int
+	Index int
+	// Siblings from the leaf level up to the level below the root
+	Path []*big.Int
+}
+
+// Build_tree builds the tree natively and returns the levels from the leaves (hashed) to the root
+func Build_tree(hasher *Hasher, leaves []*big.Int) ([][]*big.Int, error) {
+	level := make([]*big.Int, len(leaves))
+	for i, leaf := range leaves {
+		node, err := hasher.Hash(leaf)
+		if err != nil {
+			return nil, err
+		}
+		level[i] = node
+	}
+	levels := [][]*big.Int{level}
+	for len(level) > 1 {
+		next := make([]*big.Int, len(level)/2)
+		for i := range next {
+			node, err := hasher.Hash(level[2*i], level[2*i+1])
+			if err != nil {
+				return nil, err
+			}
+			next[i] = node
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels, nil
+}
+
+// Gen_rand_inputs builds a random tree with 2^depth leaves and returns the membership proofs of n random leaves
+func Gen_rand_inputs(n int, hasher *Hasher, depth int) ([]Membership, error) {
+	if depth < 1 || depth > MAX_DEPTH {
+		return nil, fmt.Errorf("the depth of the random tree must be between 1 and %d", MAX_DEPTH)
+	}
+	// Generate random leaves
+	leaves := make([]*big.Int, 1<<depth)
+	for i := range leaves {
+		leaf, err := hasher.Rand_leaf()
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = leaf
+	}
+	levels, err := Build_tree(hasher, leaves)
+	if err != nil {
+		return nil, err
+	}
+	root := levels[depth][0]
+
+	memberships := make([]Membership, n)
+	for i := 0; i < n; i++ {
+		// Pick a random leaf and collect its siblings
+		index, err := rand_int(big.NewInt(int64(len(leaves))))
+		if err != nil {
+			return nil, err
+		}
+		memberships[i] = Membership{Root: root, Leaf: leaves[index.Int64()], Index: int(index.Int64()), Path: make([]*big.Int, depth)}
+		pos := memberships[i].Index
+		for d := 0; d < depth; d++ {
+			memberships[i].Path[d] = levels[d][pos^1]
+			pos >>= 1
+		}
+	}
+	return memberships, nil
+}
+
+// Function to read file and extract the membership proofs
+// Each line contains the root, the leaf, the index of the leaf and the siblings from the leaves to the root.
+// The index is in decimal and the nodes are in hexadecimal.
+func Parse_file(file_path string) ([]Membership, error) {
+	// Open the file
+	file, err := os.Open(file_path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var memberships []Membership
+
+	scanner := bufio.NewScanner(file)
+	// The lines get long for deep trees hashed with SHA-256
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line_num := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		line_num++
+		parts := strings.Split(line, " ")
+		if len(parts) < 4 {
+			return nil, fmt.Errorf("invalid line format: %s", line)
+		}
+		// All the paths need to have the same length to use the same circuit
+		if len(memberships) > 0 && len(parts)-3 != len(memberships[0].Path) {
+			return nil, fmt.Errorf("invalid path not %d siblings at line %d", len(memberships[0].Path), line_num)
+		}
+		var m Membership
+		var succ bool
+		// Read the root and the leaf
+		m.Root, succ = new(big.Int).SetString(parts[0], 16)
+		if !succ {
+			return nil, fmt.Errorf("error decoding root at line %d: Failed to convert string to big.Int", line_num)
+		}
+		m.Leaf, succ = new(big.Int).SetString(parts[1], 16)
+		if !succ {
+			return nil, fmt.Errorf("error decoding leaf at line %d: Failed to convert string to big.Int", line_num)
+		}
+		// Read the index
+		m.Index, err = strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("error decoding index at line %d: %v", line_num, err)
+		}
+		// Read the siblings
+		m.Path = make([]*big.Int, len(parts)-3)
+		for j := range m.Path {
+			m.Path[j], succ = new(big.Int).SetString(parts[j+3], 16)
+			if !succ {
+				return nil, fmt.Errorf("error decoding sibling %d at line %d: Failed to convert string to big.Int", j, line_num)
+			}
+		}
+		memberships = append(memberships, m)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+// MerkleCircuit defines a proof of membership in a Merkle tree
+// The root is public, the leaf, its index and the path are secret
+type MerkleCircuit struct {
+	Root  []frontend.Variable `gnark:",public"`
+	Leaf  []frontend.Variable
+	Index frontend.Variable
+	Path  [][]frontend.Variable
+
+	Hasher *Hasher `gnark:"-"`
+}
+
+// Define declares the circuit's constraints
+// Root = H(...H(H(H(Leaf), Path[0]), Path[1])..., Path[depth-1]) with the order of each pair given by the bits of Index
+func (circuit *MerkleCircuit) Define(api frontend.API) error {
+	node, err := circuit.Hasher.Hash_circuit(api, circuit.Leaf)
+	if err != nil {
+		return err
+	}
+	// Bit d of the index is 1 when the node is the right child at level d
+	index_bits := api.ToBinary(circuit.Index, len(circuit.Path))
+	for d, sibling := range circuit.Path {
+		left := make([]frontend.Variable, len(node))
+		right := make([]frontend.Variable, len(node))
+		for i := range node {
+			left[i] = api.Select(index_bits[d], sibling[i], node[i])
+			right[i] = api.Select(index_bits[d], node[i], sibling[i])
+		}
+		node, err = circuit.Hasher.Hash_circuit(api, left, right)
+		if err != nil {
+			return err
+		}
+	}
+	if len(node) != len(circuit.Root) {
+		return fmt.Errorf("the root has %d variables instead of %d", len(circuit.Root), len(node))
+	}
+	for i := range node {
+		api.AssertIsEqual(circuit.Root[i], node[i])
+	}
+	return nil
+}
+
+func (hasher *Hasher) assignment(m Membership) *MerkleCircuit {
+	path := make([][]frontend.Variable, len(m.Path))
+	for d := range path {
+		path[d] = hasher.To_variables(m.Path[d])
+	}
+	return &MerkleCircuit{Root: hasher.To_variables(m.Root), Leaf: hasher.To_variables(m.Leaf), Index: m.Index, Path: path}
+}
+
+// New_circuit returns the circuit for the membership proofs in a tree of the given depth
+func New_circuit(hasher *Hasher, depth int) *MerkleCircuit {
+	path := make([][]frontend.Variable, depth)
+	for d := range path {
+		path[d] = make([]frontend.Variable, hasher.Node_size())
+	}
+	return &MerkleCircuit{
+		Root:   make([]frontend.Variable, hasher.Node_size()),
+		Leaf:   make([]frontend.Variable, hasher.Node_size()),
+		Path:   path,
+		Hasher: hasher,
+	}
+}
+
+func Benchmark(cfg benchmark.Config, hasher *Hasher, memberships []Membership) error {
+	if len(memberships) == 0 {
+		fmt.Println("No membership proofs were given. Please check your input!")
+		return nil
+	}
+	depth := len(memberships[0].Path)
+
+	// Create the circuit assignments
+	// The invalid assignments use a wrong root so that the constraints cannot be satisfied
+	assignments := make([]frontend.Circuit, len(memberships))
+	invalid_assignments := make([]frontend.Circuit, len(memberships))
+	for i, m := range memberships {
+		if len(m.Path) != depth {
+			return fmt.Errorf("all the paths must have %d siblings", depth)
+		}
+		assignments[i] = hasher.assignment(m)
+		wrong := m
+		wrong.Root = new(big.Int).Xor(m.Root, big.NewInt(1))
+		invalid_assignments[i] = hasher.assignment(wrong)
+	}
+
+	cfg.Circuit_params = hasher.Circuit_params()
+	cfg.Circuit_params["Merkle tree depth"] = depth
+	return benchmark.Run(cfg, "merkle_"+hasher.Name, New_circuit(hasher, depth), assignments, invalid_assignments)
+}
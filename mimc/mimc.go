@@ -0,0 +1,175 @@
+package mimc
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark/frontend"
+	std_mimc "github.com/consensys/gnark/std/hash/mimc"
+
+	"gnark_on_icicle/benchmark"
+)
+
+/* Helper functions */
+
+// Get the native MiMC hash function that matches the one used in the circuit for the curve
+func Get_hash(curve_id ecc.ID) (hash.Hash, error) {
+	switch curve_id {
+	case ecc.BN254:
+		return hash.MIMC_BN254, nil
+	case ecc.BLS12_377:
+		return hash.MIMC_BLS12_377, nil
+	case ecc.BLS12_381:
+		return hash.MIMC_BLS12_381, nil
+	case ecc.BW6_761:
+		return hash.MIMC_BW6_761, nil
+	default:
+		return 0, fmt.Errorf("curve %s is not supported", curve_id.String())
+	}
+}
+
+// Hash_elements computes the MiMC hash of field elements, the same way as Write(elements...) then Sum() in the circuit
+func Hash_elements(h hash.Hash, elements ...*big.Int) *big.Int {
+	hasher := h.New()
+	buf := make([]byte, hasher.BlockSize())
+	for _, x := range elements {
+		x.FillBytes(buf)
+		hasher.Write(buf)
+	}
+	return new(big.Int).SetBytes(hasher.Sum(nil))
+}
+
+// Compute_chain computes the k-fold iterated MiMC hash of the seed
+func Compute_chain(h hash.Hash, seed *big.Int, k int) *big.Int {
+	digest := seed
+	for i := 0; i < k; i++ {
+		digest = Hash_elements(h, digest)
+	}
+	return digest
+}
+
+func Gen_rand_inputs(n int, curve_id ecc.ID, k int) ([]*big.Int, []*big.Int, error) {
+	h, err := Get_hash(curve_id)
+	if err != nil {
+		return nil, nil, err
+	}
+	digests := make([]*big.Int, n)
+	seeds := make([]*big.Int, n)
+
+	for i := 0; i < n; i++ {
+		// Generate a random field element
+		seeds[i], err = rand.Int(rand.Reader, curve_id.ScalarField())
+		if err != nil {
+			return nil, nil, err
+		}
+		// Calculate the digest at the end of the chain
+		digests[i] = Compute_chain(h, seeds[i], k)
+	}
+
+	return digests, seeds, nil
+}
+
+// Function to read file and extract digests and seeds
+func Parse_file(file_path string) ([]*big.Int, []*big.Int, error) {
+	// Open the file
+	file, err := os.Open(file_path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var digests []*big.Int
+	var seeds []*big.Int
+
+	scanner := bufio.NewScanner(file)
+	line_num := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		line_num++
+		parts := strings.Split(line, " ")
+		if len(parts) != 2 {
+			return nil, nil, fmt.Errorf("invalid line format: %s", line)
+		}
+		// Read the digest
+		digest, succ := new(big.Int).SetString(parts[0], 10)
+		if !succ {
+			return nil, nil, fmt.Errorf("error decoding digest at line %d: Failed to convert string to big.Int", line_num)
+		}
+		// Read the seed
+		seed, succ := new(big.Int).SetString(parts[1], 10)
+		if !succ {
+			return nil, nil, fmt.Errorf("error decoding seed at line %d: Failed to convert string to big.Int", line_num)
+		}
+		digests = append(digests, digest)
+		seeds = append(seeds, seed)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return digests, seeds, nil
+}
+
+// MiMCChainCircuit defines a proof of knowledge of the seed of a MiMC hash chain
+// MiMC^K(secret Seed) = public Digest
+type MiMCChainCircuit struct {
+	Seed   frontend.Variable
+	Digest frontend.Variable `gnark:",public"`
+
+	K int `gnark:"-"`
+}
+
+// Define declares the circuit's constraints
+// Digest = MiMC(MiMC(...MiMC(Seed)))
+func (circuit *MiMCChainCircuit) Define(api frontend.API) error {
+	digest := circuit.Seed
+	for i := 0; i < circuit.K; i++ {
+		h, err := std_mimc.NewMiMC(api)
+		if err != nil {
+			return err
+		}
+		h.Write(digest)
+		digest = h.Sum()
+	}
+	api.AssertIsEqual(circuit.Digest, digest)
+	return nil
+}
+
+// New_circuit returns the circuit for a chain of k hashes
+func New_circuit(k int) *MiMCChainCircuit {
+	return &MiMCChainCircuit{K: k}
+}
+
+func Benchmark(cfg benchmark.Config, k int, digests []*big.Int, seeds []*big.Int) error {
+	// Check if we have the same number of digests and seeds
+	if len(digests) != len(seeds) {
+		fmt.Println("The number of digests and seeds are not equal. Please check your input!")
+		return nil
+	}
+	if len(digests) == 0 {
+		fmt.Println("No inputs were given. Please check your input!")
+		return nil
+	}
+	if k < 1 {
+		return fmt.Errorf("the length of the chain must be positive")
+	}
+
+	// Create the circuit assignments
+	// The invalid assignments use a wrong digest so that the constraint cannot be satisfied
+	assignments := make([]frontend.Circuit, len(digests))
+	invalid_assignments := make([]frontend.Circuit, len(digests))
+	for i := 0; i < len(digests); i++ {
+		assignments[i] = &MiMCChainCircuit{Seed: seeds[i], Digest: digests[i]}
+		invalid_assignments[i] = &MiMCChainCircuit{Seed: seeds[i], Digest: new(big.Int).Add(digests[i], big.NewInt(1))}
+	}
+
+	cfg.Circuit_params = map[string]int{"MiMC chain length": k}
+	return benchmark.Run(cfg, "mimc", New_circuit(k), assignments, invalid_assignments)
+}
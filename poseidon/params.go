@@ -0,0 +1,283 @@
+package poseidon
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Security level (in bits) of the round numbers
+const SECURITY_LEVEL = 128
+
+// Maximum width of the permutation (the widths of circomlib)
+const MAX_WIDTH = 17
+
+// Number of partial rounds of the reference parameters of the x^5 permutation for the widths 2 to 17 (circomlib on
+// bn254, and the same numbers in the test vectors of the reference implementation for 255-bit fields)
+var x5_partial_rounds = []int{56, 57, 56, 60, 60, 63, 64, 63, 60, 66, 60, 65, 70, 60, 64, 68}
+
+// Exponents tried for the S-box, the first one that is coprime with modulus-1 is used
+var sbox_exponents = []int{5, 7, 11, 13, 17}
+
+// Params holds the parameters of the Poseidon permutation for a given width and scalar field
+// The round numbers of the x^5 permutation are the reference ones for fields of at least 254 bits, the other round
+// numbers are computed with the security inequalities of the Poseidon paper. The round constants and the MDS matrix
+// are generated with the Grain LFSR of the reference implementation, so the parameters of bn254 are circomlib's.
+type Params struct {
+	Width           int
+	Alpha           int
+	Full_rounds     int
+	Partial_rounds  int
+	Modulus         *big.Int
+	Round_constants [][]*big.Int // one row of Width constants per round
+	MDS             [][]*big.Int
+}
+
+func New_params(width int, modulus *big.Int) (*Params, error) {
+	if width < 2 || width > MAX_WIDTH {
+		return nil, fmt.Errorf("the width must be between 2 and %d", MAX_WIDTH)
+	}
+	p := &Params{
+		Width:   width,
+		Modulus: new(big.Int).Set(modulus),
+	}
+
+	// Pick the smallest exponent such that x -> x^alpha is a permutation of the field
+	p_minus_one := new(big.Int).Sub(modulus, big.NewInt(1))
+	for _, alpha := range sbox_exponents {
+		if new(big.Int).GCD(nil, nil, big.NewInt(int64(alpha)), p_minus_one).Cmp(big.NewInt(1)) == 0 {
+			p.Alpha = alpha
+			break
+		}
+	}
+	if p.Alpha == 0 {
+		return nil, fmt.Errorf("no S-box exponent found for the field")
+	}
+	p.Full_rounds, p.Partial_rounds = Round_numbers(modulus, width, p.Alpha)
+	// With 254 bits or more, the bounds only depend on the security level so the reference parameters apply
+	if p.Alpha == 5 && modulus.BitLen() >= 254 {
+		p.Partial_rounds = x5_partial_rounds[width-2]
+	}
+
+	// The Grain LFSR is seeded with the parameters, then it gives the round constants followed by the MDS matrix
+	n := modulus.BitLen()
+	g := new_grain(n, width, p.Full_rounds, p.Partial_rounds)
+	nb_rounds := p.Full_rounds + p.Partial_rounds
+	p.Round_constants = make([][]*big.Int, nb_rounds)
+	for r := 0; r < nb_rounds; r++ {
+		p.Round_constants[r] = make([]*big.Int, width)
+		for i := 0; i < width; i++ {
+			// Rejection sampling of the constants that are not in the field
+			c := g.random_bits(n)
+			for c.Cmp(modulus) >= 0 {
+				c = g.random_bits(n)
+			}
+			p.Round_constants[r][i] = c
+		}
+	}
+	p.MDS = new_mds(g, modulus, width)
+	return p, nil
+}
+
+// Round_numbers returns the number of full and partial rounds of the permutation for SECURITY_LEVEL bits of security
+// The smallest numbers of rounds satisfying the inequalities of the statistical, interpolation and Gröbner basis
+// attacks (the number of S-boxes is the smallest with the smallest number of full rounds) are increased by the
+// security margin of the reference implementation: 2 more full rounds and 7.5% more partial rounds. The reference
+// parameters of the x^5 permutation have a few more partial rounds for some widths.
+func Round_numbers(modulus *big.Int, width int, alpha int) (int, int) {
+	M := float64(SECURITY_LEVEL)
+	n := float64(modulus.BitLen())
+	t := float64(width)
+	modulus_float, _ := new(big.Float).SetInt(modulus).Float64()
+	log2_p := math.Log2(modulus_float)
+	// log_alpha(2)
+	log_alpha_2 := math.Log(2) / math.Log(float64(alpha))
+
+	// Statistical attacks
+	full_rounds := 10
+	if M <= math.Floor(log2_p-float64(alpha-1)/2)*(t+1) {
+		full_rounds = 6
+	}
+
+	// Interpolation attack: R_F + R_P >= ceil(log_alpha(2) * min(M, n)) + ceil(log_alpha(t))
+	log_alpha_t := 0
+	for power := 1; power < width; power *= alpha {
+		log_alpha_t++
+	}
+	min_rounds := int(math.Ceil(log_alpha_2*math.Min(M, n))) + log_alpha_t
+	// Gröbner basis attacks: R_F + R_P >= log_alpha(2) * min(M/3, log2(p)/2)
+	// and R_F + R_P >= t - 1 + log_alpha(2) * min(M/(t+1), log2(p)/2)
+	if r := int(math.Ceil(log_alpha_2 * math.Min(M/3, log2_p/2))); r > min_rounds {
+		min_rounds = r
+	}
+	if r := int(math.Ceil(t - 1 + log_alpha_2*math.Min(M/(t+1), log2_p/2))); r > min_rounds {
+		min_rounds = r
+	}
+	partial_rounds := 0
+	if min_rounds > full_rounds {
+		partial_rounds = min_rounds - full_rounds
+	}
+
+	// Security margin: ceil(1.075 * R_P) computed on integers
+	full_rounds += 2
+	partial_rounds = (partial_rounds*43 + 39) / 40
+	return full_rounds, partial_rounds
+}
+
+// grain is the self-shrinking Grain LFSR used by the reference implementation of Poseidon to generate the parameters
+type grain struct {
+	// The 80 bits of the register, bits[pos] is the oldest one
+	bits [80]uint8
+	pos  int
+}
+
+// new_grain initializes the register with the parameters of the permutation over a prime field with a x^alpha S-box:
+// 2 bits for the type of field (1), 4 bits for the S-box (0), 12 bits for the size of the field, 12 bits for the
+// width, 10 bits for each round number and 30 bits set to 1. The first 160 bits are discarded.
+func new_grain(n int, width int, full_rounds int, partial_rounds int) *grain {
+	g := &grain{}
+	i := 0
+	push := func(value int, nb_bits int) {
+		for b := nb_bits - 1; b >= 0; b-- {
+			g.bits[i] = uint8(value>>b) & 1
+			i++
+		}
+	}
+	push(1, 2)
+	push(0, 4)
+	push(n, 12)
+	push(width, 12)
+	push(full_rounds, 10)
+	push(partial_rounds, 10)
+	for i < len(g.bits) {
+		g.bits[i] = 1
+		i++
+	}
+	for j := 0; j < 160; j++ {
+		g.next_bit()
+	}
+	return g
+}
+
+// next_bit shifts the register and returns the new bit
+func (g *grain) next_bit() uint8 {
+	at := func(j int) uint8 { return g.bits[(g.pos+j)%len(g.bits)] }
+	bit := at(62) ^ at(51) ^ at(38) ^ at(23) ^ at(13) ^ at(0)
+	g.bits[g.pos] = bit
+	g.pos = (g.pos + 1) % len(g.bits)
+	return bit
+}
+
+// output_bit returns the next output bit: the bits are read in pairs and the second bit of a pair is output only when
+// the first bit is 1
+func (g *grain) output_bit() uint8 {
+	for g.next_bit() == 0 {
+		g.next_bit()
+	}
+	return g.next_bit()
+}
+
+// random_bits returns the integer made of the next nb_bits output bits, the first bit being the most significant
+func (g *grain) random_bits(nb_bits int) *big.Int {
+	res := new(big.Int)
+	for i := 0; i < nb_bits; i++ {
+		res.Lsh(res, 1)
+		if g.output_bit() == 1 {
+			res.SetBit(res, 0, 1)
+		}
+	}
+	return res
+}
+
+// new_mds returns the Cauchy matrix M[i][j] = 1/(x_i + y_j) where the x_i and y_j are drawn from the Grain LFSR
+// The elements are drawn again while they are not pairwise distinct or some x_i + y_j is 0. The reference script also
+// rejects the matrices with invariant subspace trails, this check is not done here but the matrices of the reference
+// test vectors are the first ones drawn.
+func new_mds(g *grain, modulus *big.Int, width int) [][]*big.Int {
+	n := modulus.BitLen()
+	for {
+		var elements []*big.Int
+		for distinct := false; !distinct; {
+			elements = make([]*big.Int, 2*width)
+			seen := make(map[string]bool)
+			distinct = true
+			for i := range elements {
+				elements[i] = g.random_bits(n)
+				elements[i].Mod(elements[i], modulus)
+				distinct = distinct && !seen[elements[i].String()]
+				seen[elements[i].String()] = true
+			}
+		}
+		xs, ys := elements[:width], elements[width:]
+
+		mds := make([][]*big.Int, width)
+		valid := true
+		for i := 0; i < width && valid; i++ {
+			mds[i] = make([]*big.Int, width)
+			for j := 0; j < width && valid; j++ {
+				sum := new(big.Int).Add(xs[i], ys[j])
+				sum.Mod(sum, modulus)
+				if sum.Sign() == 0 {
+					valid = false
+				}
+				mds[i][j] = sum.ModInverse(sum, modulus)
+			}
+		}
+		if valid {
+			return mds
+		}
+	}
+}
+
+// Permute applies the Poseidon permutation on the state in place
+func (p *Params) Permute(state []*big.Int) {
+	alpha := big.NewInt(int64(p.Alpha))
+	half_full_rounds := p.Full_rounds / 2
+	for r := 0; r < p.Full_rounds+p.Partial_rounds; r++ {
+		// Add the round constants
+		for i := range state {
+			state[i].Add(state[i], p.Round_constants[r][i])
+			state[i].Mod(state[i], p.Modulus)
+		}
+		// Apply the S-box on the whole state for the full rounds and on the first element for the partial rounds
+		if r < half_full_rounds || r >= half_full_rounds+p.Partial_rounds {
+			for i := range state {
+				state[i].Exp(state[i], alpha, p.Modulus)
+			}
+		} else {
+			state[0].Exp(state[0], alpha, p.Modulus)
+		}
+		// Multiply by the MDS matrix
+		res := make([]*big.Int, p.Width)
+		for i := 0; i < p.Width; i++ {
+			res[i] = new(big.Int)
+			for j := 0; j < p.Width; j++ {
+				res[i].Add(res[i], new(big.Int).Mul(p.MDS[i][j], state[j]))
+			}
+			res[i].Mod(res[i], p.Modulus)
+		}
+		copy(state, res)
+	}
+}
+
+// Hash absorbs the elements in a sponge with a capacity of one element and returns the first element of the rate
+// The capacity element is initialized with the number of elements to separate inputs of different lengths
+func (p *Params) Hash(elements []*big.Int) *big.Int {
+	state := make([]*big.Int, p.Width)
+	state[0] = big.NewInt(int64(len(elements)))
+	for i := 1; i < p.Width; i++ {
+		state[i] = new(big.Int)
+	}
+	rate := p.Width - 1
+	for start := 0; start < len(elements); start += rate {
+		for j := 0; j < rate && start+j < len(elements); j++ {
+			state[1+j].Add(state[1+j], elements[start+j])
+			state[1+j].Mod(state[1+j], p.Modulus)
+		}
+		p.Permute(state)
+	}
+	if len(elements) == 0 {
+		p.Permute(state)
+	}
+	return state[1]
+}
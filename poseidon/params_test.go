@@ -0,0 +1,90 @@
+package poseidon
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+// The computed round numbers of the x^5 permutation must not exceed the reference ones used instead on bn254
+func TestRound_numbers_x5(t *testing.T) {
+	for width := 2; width <= MAX_WIDTH; width++ {
+		full_rounds, partial_rounds := Round_numbers(ecc.BN254.ScalarField(), width, 5)
+		if full_rounds != 8 || partial_rounds > x5_partial_rounds[width-2] {
+			t.Errorf("width %d: got %d full and %d partial rounds, expected 8 and at most %d", width, full_rounds,
+				partial_rounds, x5_partial_rounds[width-2])
+		}
+		params, err := New_params(width, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if params.Full_rounds != 8 || params.Partial_rounds != x5_partial_rounds[width-2] {
+			t.Errorf("width %d: the parameters of bn254 are not the ones of circomlib", width)
+		}
+	}
+}
+
+// The known answers are the outputs of the permutation on [0, 1, ..., width-1] of the reference implementation
+// (poseidonperm_x5_254_3, poseidonperm_x5_254_5 and poseidonperm_x5_255_3), the bn254 ones are also the circomlib
+// hashes of [1, 2] and [1, 2, 3, 4]
+func TestPermute_known_answers(t *testing.T) {
+	tests := []struct {
+		curve    ecc.ID
+		width    int
+		expected []string
+	}{
+		{ecc.BN254, 3, []string{
+			"115cc0f5e7d690413df64c6b9662e9cf2a3617f2743245519e19607a4417189a",
+			"0fca49b798923ab0239de1c9e7a4a9a2210312b6a2f616d18b5a87f9b628ae29",
+			"0e7ae82e40091e63cbd4f16a6d16310b3729d4b6e138fcf54110e2867045a30c",
+		}},
+		{ecc.BN254, 5, []string{
+			"299c867db6c1fdd79dcefa40e4510b9837e60ebb1ce0663dbaa525df65250465",
+		}},
+		{ecc.BLS12_381, 3, []string{
+			"28ce19420fc246a05553ad1e8c98f5c9d67166be2c18e9e4cb4b4e317dd2a78a",
+			"51f3e312c95343a896cfd8945ea82ba956c1118ce9b9859b6ea56637b4b1ddc4",
+			"3b2b69139b235626a0bfb56c9527ae66a7bf486ad8c11c14d1da0c69bbe0f79a",
+		}},
+	}
+	for _, tc := range tests {
+		params, err := New_params(tc.width, tc.curve.ScalarField())
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := make([]*big.Int, tc.width)
+		for i := range state {
+			state[i] = big.NewInt(int64(i))
+		}
+		params.Permute(state)
+		for i, hex := range tc.expected {
+			expected, _ := new(big.Int).SetString(hex, 16)
+			if state[i].Cmp(expected) != 0 {
+				t.Errorf("%s width %d: element %d of the permutation is %x, expected %s", tc.curve, tc.width, i, state[i], hex)
+			}
+		}
+	}
+}
+
+func TestCircuit_matches_hash(t *testing.T) {
+	for _, curve := range []ecc.ID{ecc.BN254, ecc.BLS12_377} {
+		params, err := New_params(3, curve.ScalarField())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, preimages, err := Gen_rand_inputs(1, params, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assignment := &PoseidonCircuit{PreImage: make([]frontend.Variable, 5), Hash: params.Hash(preimages[0])}
+		for i := range preimages[0] {
+			assignment.PreImage[i] = preimages[0][i]
+		}
+		if err := test.IsSolved(New_circuit(params, 5), assignment, curve.ScalarField()); err != nil {
+			t.Errorf("%s: %v", curve, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package poseidon
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark/frontend"
+
+	"gnark_on_icicle/benchmark"
+)
+
+func Gen_rand_inputs(n int, params *Params, nb_elements int) ([]*big.Int, [][]*big.Int, error) {
+	hashes := make([]*big.Int, n)
+	preimages := make([][]*big.Int, n)
+
+	for i := 0; i < n; i++ {
+		// Generate random field elements
+		preimages[i] = make([]*big.Int, nb_elements)
+		for j := 0; j < nb_elements; j++ {
+			elem, err := rand.Int(rand.Reader, params.Modulus)
+			if err != nil {
+				return nil, nil, err
+			}
+			preimages[i][j] = elem
+		}
+		// Calculate the Poseidon hash
+		hashes[i] = params.Hash(preimages[i])
+	}
+
+	return hashes, preimages, nil
+}
+
+// Function to read file and extract hashes and preimages
+// Each line contains the hash followed by the elements of the pre-image, all in decimal
+func Parse_file(file_path string) ([]*big.Int, [][]*big.Int, error) {
+	// Open the file
+	file, err := os.Open(file_path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var hashes []*big.Int
+	var preimages [][]*big.Int
+
+	scanner := bufio.NewScanner(file)
+	line_num := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		line_num++
+		parts := strings.Split(line, " ")
+		if len(parts) < 2 {
+			return nil, nil, fmt.Errorf("invalid line format: %s", line)
+		}
+		// All the pre-images need to have the same number of elements to use the same circuit
+		if len(preimages) > 0 && len(parts)-1 != len(preimages[0]) {
+			return nil, nil, fmt.Errorf("invalid pre-image not %d elements at line %d", len(preimages[0]), line_num)
+		}
+		// Read the hash
+		hash, succ := new(big.Int).SetString(parts[0], 10)
+		if !succ {
+			return nil, nil, fmt.Errorf("error decoding hash at line %d: Failed to convert string to big.Int", line_num)
+		}
+		// Read the elements of the pre-image
+		preimage := make([]*big.Int, len(parts)-1)
+		for j := range preimage {
+			preimage[j], succ = new(big.Int).SetString(parts[j+1], 10)
+			if !succ {
+				return nil, nil, fmt.Errorf("error decoding pre-image element %d at line %d: Failed to convert string to big.Int", j, line_num)
+			}
+		}
+		hashes = append(hashes, hash)
+		preimages = append(preimages, preimage)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return hashes, preimages, nil
+}
+
+// PoseidonCircuit defines a pre-image knowledge proof
+// Poseidon(secret PreImage) = public Hash
+type PoseidonCircuit struct {
+	PreImage []frontend.Variable
+	Hash     frontend.Variable `gnark:",public"`
+
+	Params *Params `gnark:"-"`
+}
+
+// Define declares the circuit's constraints
+// Hash = Poseidon(PreImage)
+func (circuit *PoseidonCircuit) Define(api frontend.API) error {
+	p := circuit.Params
+	if api.Compiler().Field().Cmp(p.Modulus) != 0 {
+		return fmt.Errorf("the Poseidon parameters were generated for another field")
+	}
+	api.AssertIsEqual(circuit.Hash, Hash_circuit(api, p, circuit.PreImage))
+	return nil
+}
+
+// Hash_circuit absorbs the elements in the circuit the same way as Params.Hash
+func Hash_circuit(api frontend.API, p *Params, elements []frontend.Variable) frontend.Variable {
+	state := make([]frontend.Variable, p.Width)
+	state[0] = len(elements)
+	for i := 1; i < p.Width; i++ {
+		state[i] = 0
+	}
+	rate := p.Width - 1
+	for start := 0; start < len(elements); start += rate {
+		for j := 0; j < rate && start+j < len(elements); j++ {
+			state[1+j] = api.Add(state[1+j], elements[start+j])
+		}
+		state = permute(api, p, state)
+	}
+	if len(elements) == 0 {
+		state = permute(api, p, state)
+	}
+	return state[1]
+}
+
+// permute applies the Poseidon permutation in the circuit
+func permute(api frontend.API, p *Params, state []frontend.Variable) []frontend.Variable {
+	half_full_rounds := p.Full_rounds / 2
+	for r := 0; r < p.Full_rounds+p.Partial_rounds; r++ {
+		// Add the round constants
+		for i := range state {
+			state[i] = api.Add(state[i], p.Round_constants[r][i])
+		}
+		// Apply the S-box on the whole state for the full rounds and on the first element for the partial rounds
+		if r < half_full_rounds || r >= half_full_rounds+p.Partial_rounds {
+			for i := range state {
+				state[i] = sbox(api, state[i], p.Alpha)
+			}
+		} else {
+			state[0] = sbox(api, state[0], p.Alpha)
+		}
+		// Multiply by the MDS matrix, this only creates linear expressions
+		res := make([]frontend.Variable, p.Width)
+		for i := 0; i < p.Width; i++ {
+			res[i] = api.Mul(p.MDS[i][0], state[0])
+			for j := 1; j < p.Width; j++ {
+				res[i] = api.Add(res[i], api.Mul(p.MDS[i][j], state[j]))
+			}
+		}
+		state = res
+	}
+	return state
+}
+
+// sbox computes x^alpha with square and multiply
+func sbox(api frontend.API, x frontend.Variable, alpha int) frontend.Variable {
+	var res frontend.Variable
+	square := x
+	for e := alpha; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			if res == nil {
+				res = square
+			} else {
+				res = api.Mul(res, square)
+			}
+		}
+		if e > 1 {
+			square = api.Mul(square, square)
+		}
+	}
+	return res
+}
+
+// New_circuit returns the circuit hashing pre-images of nb_elements field elements
+func New_circuit(params *Params, nb_elements int) *PoseidonCircuit {
+	return &PoseidonCircuit{PreImage: make([]frontend.Variable, nb_elements), Params: params}
+}
+
+func Benchmark(cfg benchmark.Config, params *Params, hashes []*big.Int, preimages [][]*big.Int) error {
+	// Check if we have the same number of hashes and preimages
+	if len(hashes) != len(preimages) {
+		fmt.Println("The number of hashes and pre-images are not equal. Please check your input!")
+		return nil
+	}
+	if len(preimages) == 0 {
+		fmt.Println("No pre-images were given. Please check your input!")
+		return nil
+	}
+	if params.Modulus.Cmp(cfg.Curve_id.ScalarField()) != 0 {
+		return fmt.Errorf("the Poseidon parameters were generated for another field")
+	}
+	nb_elements := len(preimages[0])
+
+	// Create the circuit assignments
+	// The invalid assignments use a wrong hash so that the constraints cannot be satisfied
+	assignments := make([]frontend.Circuit, len(hashes))
+	invalid_assignments := make([]frontend.Circuit, len(hashes))
+	for i := 0; i < len(hashes); i++ {
+		if len(preimages[i]) != nb_elements {
+			return fmt.Errorf("all the pre-images must have %d elements", nb_elements)
+		}
+		preimage := make([]frontend.Variable, nb_elements)
+		for j := range preimage {
+			preimage[j] = preimages[i][j]
+		}
+		assignments[i] = &PoseidonCircuit{PreImage: preimage, Hash: hashes[i]}
+		invalid_assignments[i] = &PoseidonCircuit{PreImage: preimage, Hash: new(big.Int).Add(hashes[i], big.NewInt(1))}
+	}
+
+	cfg.Circuit_params = map[string]int{"Poseidon width": params.Width, "Poseidon alpha": params.Alpha,
+		"Poseidon partial rounds": params.Partial_rounds, "Poseidon number of elements": nb_elements}
+	return benchmark.Run(cfg, "poseidon", New_circuit(params, nb_elements), assignments, invalid_assignments)
+}
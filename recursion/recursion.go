@@ -0,0 +1,239 @@
+package recursion
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	"github.com/consensys/gnark-crypto/ecc/bls12-377/fr/hash_to_field"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bls12377 "github.com/consensys/gnark/backend/groth16/bls12-377"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra"
+	"github.com/consensys/gnark/std/algebra/native/sw_bls12377"
+	"github.com/consensys/gnark/std/math/emulated"
+	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+
+	"gnark_on_icicle/benchmark"
+	"gnark_on_icicle/constants"
+	"gnark_on_icicle/cubic"
+	"gnark_on_icicle/sha256"
+)
+
+// The inner proofs are generated on BLS12-377 and verified in a circuit on BW6-761, whose scalar field is the
+// base field of BLS12-377. The arithmetic of the in-circuit verifier is then native.
+const INNER_CURVE = ecc.BLS12_377
+const OUTER_CURVE = ecc.BW6_761
+
+// Inner circuits that can be verified recursively
+var Inner_circuits = []string{"cubic", "sha256"}
+
+// Inner_params holds the inner circuit and its runtime parameters
+type Inner_params struct {
+	Circuit string
+	// cubic
+	Batch int
+	// sha256, the circuit is compiled for Max_preimage_size bytes or Preimage_size bytes if it is 0
+	Preimage_size     int
+	Max_preimage_size int
+}
+
+type (
+	Inner_proof         = stdgroth16.Proof[sw_bls12377.G1Affine, sw_bls12377.G2Affine]
+	Inner_verifying_key = stdgroth16.VerifyingKey[sw_bls12377.G1Affine, sw_bls12377.G2Affine, sw_bls12377.GT]
+	Inner_witness       = stdgroth16.Witness[sw_bls12377.ScalarField]
+)
+
+// RecursionCircuit verifies a groth16 proof of the inner circuit
+// The public inputs of the inner proof are public, the proof and the verifying key are secret.
+// When the inner circuit uses a commitment, the hash of the commitment is the last public input of the inner
+// witness and the commitment is added to the verification like in the native verifier.
+type RecursionCircuit struct {
+	Proof        Inner_proof
+	VerifyingKey Inner_verifying_key
+	InnerWitness Inner_witness `gnark:",public"`
+	Commitment   sw_bls12377.G1Affine
+
+	With_commitment bool `gnark:"-"`
+}
+
+// Define declares the circuit's constraints
+// groth16.Verify(Proof, VerifyingKey, InnerWitness) == true
+func (circuit *RecursionCircuit) Define(api frontend.API) error {
+	curve, err := algebra.GetCurve[sw_bls12377.ScalarField, sw_bls12377.G1Affine](api)
+	if err != nil {
+		return err
+	}
+	pairing, err := algebra.GetPairing[sw_bls12377.G1Affine, sw_bls12377.G2Affine, sw_bls12377.GT](api)
+	if err != nil {
+		return err
+	}
+	verifier := stdgroth16.NewVerifier(curve, pairing)
+	if circuit.With_commitment {
+		return verifier.AssertProofWithCommitment(circuit.VerifyingKey, circuit.Proof, circuit.Commitment, circuit.InnerWitness)
+	}
+	return verifier.AssertProof(circuit.VerifyingKey, circuit.Proof, circuit.InnerWitness)
+}
+
+// inner_assignments builds the assignments of the inner circuit from random inputs
+// It also returns the parameters of the inner circuit written in the benchmark parameters of the inner layer.
+func inner_assignments(inner Inner_params, n int) (string, frontend.Circuit, []frontend.Circuit, []frontend.Circuit, map[string]int, error) {
+	assignments := make([]frontend.Circuit, n)
+	invalid_assignments := make([]frontend.Circuit, n)
+	switch inner.Circuit {
+	case "cubic":
+		x, y, err := cubic.Gen_rand_inputs(n, inner.Batch)
+		if err != nil {
+			return "", nil, nil, nil, nil, err
+		}
+		for i := 0; i < n; i++ {
+			assignments[i] = cubic.New_assignment(x[i], y[i])
+			wrong := cubic.New_assignment(x[i], y[i])
+			wrong.Y[0] = new(big.Int).Add(y[i][0], big.NewInt(1))
+			invalid_assignments[i] = wrong
+		}
+		return "cubic", cubic.New_circuit(inner.Batch), assignments, invalid_assignments,
+			map[string]int{"Batch size": inner.Batch, "Cubic X_SIZE": constants.X_SIZE_CUBIC}, nil
+	case "sha256":
+		max_size := inner.Max_preimage_size
+		if max_size == 0 {
+			max_size = inner.Preimage_size
+		}
+		if inner.Preimage_size > max_size {
+			return "", nil, nil, nil, nil, fmt.Errorf("the pre-images have %d bytes, more than the maximum size %d", inner.Preimage_size, max_size)
+		}
+		hashes, preimages, err := sha256.Gen_rand_inputs(n, inner.Preimage_size)
+		if err != nil {
+			return "", nil, nil, nil, nil, err
+		}
+		for i := 0; i < n; i++ {
+			assignments[i] = sha256.New_assignment(hashes[i], preimages[i], max_size)
+			wrong_hash := hashes[i]
+			wrong_hash[0] ^= 1
+			invalid_assignments[i] = sha256.New_assignment(wrong_hash, preimages[i], max_size)
+		}
+		return "sha256", sha256.New_circuit(max_size), assignments, invalid_assignments,
+			map[string]int{"SHA-256 max preimage size": max_size, "SHA-256 blocks": sha256.Nb_blocks(max_size)}, nil
+	default:
+		return "", nil, nil, nil, nil, fmt.Errorf("inner circuit %s unknown, the supported inner circuits are %v", inner.Circuit, Inner_circuits)
+	}
+}
+
+// commitment_hash computes the public input derived from the commitment of the proof, the same way as the native verifier
+func commitment_hash(vk *groth16_bls12377.VerifyingKey, proof *groth16_bls12377.Proof, public_witness fr_bls12377.Vector) fr_bls12377.Element {
+	h := hash_to_field.New([]byte(constraint.CommitmentDst))
+	committed := vk.PublicAndCommitmentCommitted[0]
+	public_committed := make([]*big.Int, len(committed))
+	for j := range committed {
+		public_committed[j] = public_witness[committed[j]-1].BigInt(new(big.Int))
+	}
+	h.Write(constraint.SerializeCommitment(proof.Commitments[0].Marshal(), public_committed, (fr_bls12377.Bits-1)/8+1))
+	var res fr_bls12377.Element
+	res.SetBytes(h.Sum(nil)[:fr_bls12377.Bytes])
+	return res
+}
+
+// outer_assignment converts an inner proof and its public witness into an assignment of the recursion circuit
+func outer_assignment(vk groth16.VerifyingKey, proof groth16.Proof, public_witness witness.Witness) (*RecursionCircuit, error) {
+	if proof == nil {
+		return nil, fmt.Errorf("the inner proof was not generated")
+	}
+	var a RecursionCircuit
+	var err error
+	a.VerifyingKey, err = stdgroth16.ValueOfVerifyingKey[sw_bls12377.G1Affine, sw_bls12377.G2Affine, sw_bls12377.GT](vk)
+	if err != nil {
+		return nil, err
+	}
+	a.Proof, err = stdgroth16.ValueOfProof[sw_bls12377.G1Affine, sw_bls12377.G2Affine](proof)
+	if err != nil {
+		return nil, err
+	}
+	a.InnerWitness, err = stdgroth16.ValueOfWitness[sw_bls12377.ScalarField](public_witness)
+	if err != nil {
+		return nil, err
+	}
+	a.Commitment, err = stdgroth16.ValueOfProofCommitment[sw_bls12377.G1Affine](proof)
+	if err != nil {
+		return nil, err
+	}
+	tvk := vk.(*groth16_bls12377.VerifyingKey)
+	if len(tvk.PublicAndCommitmentCommitted) > 0 {
+		vec := public_witness.Vector().(fr_bls12377.Vector)
+		res := commitment_hash(tvk, proof.(*groth16_bls12377.Proof), vec)
+		a.InnerWitness.Public = append(a.InnerWitness.Public, sw_bls12377.NewScalar(res))
+	}
+	return &a, nil
+}
+
+// outer_circuit allocates the recursion circuit for the verifying key of the inner circuit
+func outer_circuit(vk groth16.VerifyingKey) (*RecursionCircuit, error) {
+	tvk := vk.(*groth16_bls12377.VerifyingKey)
+	if len(tvk.PublicAndCommitmentCommitted) > 1 {
+		return nil, fmt.Errorf("the in-circuit verifier supports at most one commitment, the inner circuit has %d", len(tvk.PublicAndCommitmentCommitted))
+	}
+	// The verifying key has one point per public input (including the one wire) and per commitment
+	return &RecursionCircuit{
+		VerifyingKey: Inner_verifying_key{
+			G1: struct{ K []sw_bls12377.G1Affine }{K: make([]sw_bls12377.G1Affine, len(tvk.G1.K))},
+		},
+		InnerWitness:    Inner_witness{Public: make([]emulated.Element[sw_bls12377.ScalarField], len(tvk.G1.K)-1)},
+		With_commitment: len(tvk.PublicAndCommitmentCommitted) == 1,
+	}, nil
+}
+
+// Benchmark runs the benchmark of the inner circuit on BLS12-377 and then the benchmark of the circuit verifying
+// the inner proofs on BW6-761. Each layer writes its own output folder with the same phase timings.
+func Benchmark(cfg benchmark.Config, inner_params Inner_params, n int) error {
+	name, circuit, assignments, invalid_assignments, circuit_params, err := inner_assignments(inner_params, n)
+	if err != nil {
+		return err
+	}
+
+	// Inner layer
+	fmt.Println("Benchmarking the inner circuit on", INNER_CURVE.String(), "...")
+	inner_cfg := cfg
+	inner_cfg.Curve_id = INNER_CURVE
+	inner_cfg.Circuit_params = circuit_params
+	inner, err := benchmark.Run_with_result(inner_cfg, name, circuit, assignments, invalid_assignments)
+	if err != nil {
+		return err
+	}
+
+	// Outer layer
+	// The invalid assignments verify the inner proof against the public witness of the invalid inner assignment
+	fmt.Println("Benchmarking the recursive verification on", OUTER_CURVE.String(), "...")
+	outer_assignments := make([]frontend.Circuit, n)
+	invalid_outer_assignments := make([]frontend.Circuit, n)
+	for i := 0; i < n; i++ {
+		outer_assignments[i], err = outer_assignment(inner.Vk, inner.Proofs[i], inner.Public_witnesses[i])
+		if err != nil {
+			return err
+		}
+		if cfg.Negative {
+			invalid_witness, err := frontend.NewWitness(invalid_assignments[i], INNER_CURVE.ScalarField(), frontend.PublicOnly())
+			if err != nil {
+				return err
+			}
+			invalid_outer_assignments[i], err = outer_assignment(inner.Vk, inner.Proofs[i], invalid_witness)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	outer, err := outer_circuit(inner.Vk)
+	if err != nil {
+		return err
+	}
+	outer_cfg := cfg
+	outer_cfg.Curve_id = OUTER_CURVE
+	outer_cfg.Circuit_params = map[string]int{
+		"Inner constraints":   inner.Ccs.GetNbConstraints(),
+		"Inner public inputs": inner.Ccs.GetNbPublicVariables() - 1,
+		"Inner commitments":   len(inner.Vk.(*groth16_bls12377.VerifyingKey).PublicAndCommitmentCommitted),
+	}
+	_, err = benchmark.Run_with_result(outer_cfg, "recursion_"+name, outer, outer_assignments, invalid_outer_assignments)
+	return err
+}
@@ -0,0 +1,184 @@
+package sha256
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark/frontend"
+	hash_sha2 "github.com/consensys/gnark/std/hash/sha2"
+	"github.com/consensys/gnark/std/math/uints"
+
+	"gnark_on_icicle/benchmark"
+)
+
+// Longest chain tried to find the chain length of an input that does not match the chain length of the benchmark
+const MAX_CHAIN_SEARCH = 1 << 16
+
+// Compute_chain computes the k-fold iterated SHA-256 hash of the seed
+// h_0 = seed, h_i = sha256(h_{i-1})
+func Compute_chain(seed [32]byte, k int) [32]byte {
+	digest := seed
+	for i := 0; i < k; i++ {
+		digest = sha256.Sum256(digest[:])
+	}
+	return digest
+}
+
+func Gen_rand_chain_inputs(n int, k int) ([][32]byte, [][32]byte, error) {
+	digests := make([][32]byte, n)
+	seeds := make([][32]byte, n)
+
+	for i := 0; i < n; i++ {
+		// Generate a random seed
+		_, err := rand.Read(seeds[i][:])
+		if err != nil {
+			return nil, nil, err
+		}
+		// Calculate the digest at the end of the chain
+		digests[i] = Compute_chain(seeds[i], k)
+	}
+
+	return digests, seeds, nil
+}
+
+// Function to read file and extract digests and seeds
+// Each line contains the digest and the seed, both 32 bytes in hexadecimal
+func Parse_chain_file(file_path string) ([][32]byte, [][32]byte, error) {
+	// Open the file
+	file, err := os.Open(file_path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var digests [][32]byte
+	var seeds [][32]byte
+
+	scanner := bufio.NewScanner(file)
+	line_num := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		line_num++
+		parts := strings.Split(line, " ")
+		if len(parts) != 2 {
+			return nil, nil, fmt.Errorf("invalid line format: %s", line)
+		}
+		// Check if the digests and the seeds are the correct length
+		if len(parts[0]) != 32*2 {
+			return nil, nil, fmt.Errorf("invaild digest not 32 bytes at line %d", line_num)
+		}
+		if len(parts[1]) != 32*2 {
+			return nil, nil, fmt.Errorf("invaild seed not 32 bytes at line %d", line_num)
+		}
+		// Decode the digests and seeds from hexadecimal strings into byte arrays
+		digest_bytes, err := hex.DecodeString(parts[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decoding digest at line %d: %v", line_num, err)
+		}
+		seed_bytes, err := hex.DecodeString(parts[1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decoding seed at line %d: %v", line_num, err)
+		}
+		digests = append(digests, [32]byte(digest_bytes))
+		seeds = append(seeds, [32]byte(seed_bytes))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return digests, seeds, nil
+}
+
+// SHA256ChainCircuit defines a proof of knowledge of the seed of a SHA-256 hash chain
+// SHA256^K(secret Seed) = public Digest
+// Each link hashes a 32-byte digest, which takes one compression block.
+type SHA256ChainCircuit struct {
+	Seed   [32]uints.U8
+	Digest [32]uints.U8 `gnark:",public"`
+
+	K int `gnark:"-"`
+}
+
+// Define declares the circuit's constraints
+// Digest = sha256(sha256(...sha256(Seed)))
+func (circuit *SHA256ChainCircuit) Define(api frontend.API) error {
+	uapi, err := uints.New[uints.U32](api)
+	if err != nil {
+		return err
+	}
+	digest := circuit.Seed[:]
+	for i := 0; i < circuit.K; i++ {
+		h, err := hash_sha2.New(api)
+		if err != nil {
+			return err
+		}
+		h.Write(digest)
+		digest = h.Sum()
+	}
+	if len(digest) != 32 {
+		return fmt.Errorf("not 32 bytes")
+	}
+	for i := range circuit.Digest {
+		uapi.ByteAssertEq(circuit.Digest[i], digest[i])
+	}
+	return nil
+}
+
+// New_chain_circuit returns the circuit for a chain of k hashes
+func New_chain_circuit(k int) *SHA256ChainCircuit {
+	return &SHA256ChainCircuit{K: k}
+}
+
+func Benchmark_chain(cfg benchmark.Config, k int, digests [][32]byte, seeds [][32]byte) error {
+	// Check if we have the same number of digests and seeds
+	if len(digests) != len(seeds) {
+		fmt.Println("The number of digests and seeds are not equal. Please check your input!")
+		return nil
+	}
+	if len(digests) == 0 {
+		fmt.Println("No inputs were given. Please check your input!")
+		return nil
+	}
+	if k < 1 {
+		return fmt.Errorf("the length of the chain must be positive")
+	}
+	// The chain length is not stored with the inputs, the digests are checked natively so that inputs generated
+	// with another chain length fail before the compilation
+	for i := range digests {
+		if Compute_chain(seeds[i], k) != digests[i] {
+			return chain_length_error(i, k, seeds[i], digests[i])
+		}
+	}
+
+	// Create the circuit assignments
+	// The invalid assignments use a wrong digest so that the constraints cannot be satisfied
+	assignments := make([]frontend.Circuit, len(digests))
+	invalid_assignments := make([]frontend.Circuit, len(digests))
+	for i := 0; i < len(digests); i++ {
+		assignments[i] = &SHA256ChainCircuit{Seed: convert_hash_bytes_to_gnarkU8(seeds[i]), Digest: convert_hash_bytes_to_gnarkU8(digests[i])}
+		wrong_digest := digests[i]
+		wrong_digest[0] ^= 1
+		invalid_assignments[i] = &SHA256ChainCircuit{Seed: convert_hash_bytes_to_gnarkU8(seeds[i]), Digest: convert_hash_bytes_to_gnarkU8(wrong_digest)}
+	}
+
+	cfg.Circuit_params = map[string]int{"SHA-256 chain length": k}
+	return benchmark.Run(cfg, "sha256_chain", New_chain_circuit(k), assignments, invalid_assignments)
+}
+
+// chain_length_error returns the error of an input whose digest is not the end of a chain of k hashes of its seed
+// The error gives the chain length of the input if it is at most MAX_CHAIN_SEARCH.
+func chain_length_error(i int, k int, seed [32]byte, digest [32]byte) error {
+	for length := 1; length <= MAX_CHAIN_SEARCH; length++ {
+		seed = sha256.Sum256(seed[:])
+		if seed == digest {
+			return fmt.Errorf("the digest of input %d is not the end of a chain of %d hashes of its seed, the input was generated with -chain_length %d", i, k, length)
+		}
+	}
+	return fmt.Errorf("the digest of input %d is not the end of a chain of %d hashes of its seed (nor of a chain of at most %d hashes)", i, k, MAX_CHAIN_SEARCH)
+}
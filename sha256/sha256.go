@@ -2,31 +2,19 @@ package sha256
 
 import (
 	"bufio"
-	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"os"
 	"strings"
-	"time"
 
-	"github.com/consensys/gnark/std/hash/sha2"
 	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/permutation/sha2"
 
-	"github.com/consensys/gnark-crypto/ecc"
-	"github.com/consensys/gnark/backend"
-	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/frontend/cs/r1cs"
-
-	"github.com/consensys/gnark/logger"
-
-	"github.com/rs/zerolog"
 
 	"gnark_on_icicle/benchmark"
-	"gnark_on_icicle/constants"
-	"gnark_on_icicle/gpu"
 )
 
 /* Helper functions */
@@ -41,40 +29,37 @@ func convert_hash_bytes_to_gnarkU8(hash_byte_arr [32]byte) [32]uints.U8 {
 	return hash_gnarkU8_arr
 }
 
-func convert_preimage_bytes_to_gnarkU8(preimage_byte_arr [constants.PREIMAGE_SIZE]byte) [constants.PREIMAGE_SIZE]uints.U8 {
-	var preimage_gnarkU8_arr [constants.PREIMAGE_SIZE]uints.U8
-
-	for i, b := range preimage_byte_arr {
-		preimage_gnarkU8_arr[i] = uints.NewU8(uint8(b))
-	}
-
-	return preimage_gnarkU8_arr
+// Number of compression blocks needed for a pre-image of max_size bytes
+// The padding adds at least 9 bytes: 0x80 and the length of the pre-image on 8 bytes
+func Nb_blocks(max_size int) int {
+	return (max_size + 9 + 63) / 64
 }
 
-func Gen_rand_inputs(n int) ([][32]byte, [][constants.PREIMAGE_SIZE]byte, error) {
+func Gen_rand_inputs(n int, preimage_size int) ([][32]byte, [][]byte, error) {
+	if preimage_size < 0 {
+		return nil, nil, fmt.Errorf("the size of the pre-image cannot be negative")
+	}
 	rand_hashes := make([][32]byte, n)
-	rand_preimages := make([][constants.PREIMAGE_SIZE]byte, n)
+	rand_preimages := make([][]byte, n)
 
 	for i := 0; i < n; i++ {
 		// Generate random bytes
-		randomBytes := make([]byte, constants.PREIMAGE_SIZE)
-		_, err := rand.Read(randomBytes)
+		rand_preimages[i] = make([]byte, preimage_size)
+		_, err := rand.Read(rand_preimages[i])
 		if err != nil {
 			return nil, nil, err
 		}
 
-		// Convert random bytes to hex string
-		rand_preimages[i] = [constants.PREIMAGE_SIZE]byte(randomBytes)
-
 		// Calculate SHA256 hash
-		rand_hashes[i] = sha256.Sum256(randomBytes)
+		rand_hashes[i] = sha256.Sum256(rand_preimages[i])
 	}
 
 	return rand_hashes, rand_preimages, nil
 }
 
 // Function to read file and extract hashes and preimages
-func Parse_file(file_path string) ([][32]byte, [][constants.PREIMAGE_SIZE]byte, error) {
+// The pre-images can have different sizes, the circuit is compiled for the longest one
+func Parse_file(file_path string) ([][32]byte, [][]byte, error) {
 	// Open the file
 	file, err := os.Open(file_path)
 	if err != nil {
@@ -83,9 +68,11 @@ func Parse_file(file_path string) ([][32]byte, [][constants.PREIMAGE_SIZE]byte,
 	defer file.Close()
 
 	var hashes [][32]byte
-	var preimages [][constants.PREIMAGE_SIZE]byte
+	var preimages [][]byte
 
 	scanner := bufio.NewScanner(file)
+	// The lines get long with large pre-images
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
 	line_num := 0
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -98,9 +85,6 @@ func Parse_file(file_path string) ([][32]byte, [][constants.PREIMAGE_SIZE]byte,
 		if len(parts[0]) != 32*2 {
 			return nil, nil, fmt.Errorf("invaild hash not 32 bytes at line %d", line_num)
 		}
-		if len(parts[1]) != constants.PREIMAGE_SIZE*2 {
-			return nil, nil, fmt.Errorf("invaild preimage not %d bytes at line %d", constants.PREIMAGE_SIZE, line_num)
-		}
 		// Decode the hashes and preimages from hexadecimal strings into byte arrays
 		hash_bytes, err := hex.DecodeString(parts[0])
 		if err != nil {
@@ -113,7 +97,7 @@ func Parse_file(file_path string) ([][32]byte, [][constants.PREIMAGE_SIZE]byte,
 		}
 
 		hashes = append(hashes, [32]byte(hash_bytes))
-		preimages = append(preimages, [constants.PREIMAGE_SIZE]byte(preimage_bytes))
+		preimages = append(preimages, preimage_bytes)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -124,130 +108,159 @@ func Parse_file(file_path string) ([][32]byte, [][constants.PREIMAGE_SIZE]byte,
 }
 
 // Circuit defines a pre-image knowledge proof
-// SHA256(secret PreImage) = public Hash
+// SHA256(secret PreImage[:Length]) = public Hash
+// The circuit is compiled for a maximum size len(PreImage) and accepts any pre-image up to this size. The bytes
+// after Length are ignored. The padding is placed in-circuit depending on Length, all the blocks are compressed
+// and the digest of the block that holds the end of the padding is selected.
 type SHA256Circuit struct {
-	PreImage [constants.PREIMAGE_SIZE]uints.U8
+	PreImage []uints.U8
+	Length   frontend.Variable
 	Hash     [32]uints.U8 `gnark:",public"`
 }
 
 // Define declares the circuit's constraints
-// Hash = sha256(PreImage)
+// Hash = sha256(PreImage[:Length])
 func (circuit *SHA256Circuit) Define(api frontend.API) error {
-	h, err := sha2.New(api)
+	uapi, err := uints.New[uints.U32](api)
 	if err != nil {
 		return err
 	}
-	uapi, err := uints.New[uints.U32](api)
+	res, err := Variable_length_sum(api, uapi, circuit.PreImage, circuit.Length)
 	if err != nil {
 		return err
 	}
-	h.Write(circuit.PreImage[:])
-	res := h.Sum()
-	if len(res) != 32 {
-		return fmt.Errorf("not 32 bytes")
-	}
 	for i := range circuit.Hash {
 		uapi.ByteAssertEq(circuit.Hash[i], res[i])
 	}
 	return nil
 }
 
-func Benchmark(curve_id ecc.ID, GPU_Acc bool, hashes [][32]byte, preimages [][constants.PREIMAGE_SIZE]byte) error {
+// Variable_length_sum computes sha256(data[:length]) where length is a variable between 0 and len(data)
+func Variable_length_sum(api frontend.API, uapi *uints.BinaryField[uints.U32], data []uints.U8, length frontend.Variable) ([]uints.U8, error) {
+	max_size := len(data)
+	nb_blocks := Nb_blocks(max_size)
+
+	// is_end[i] == 1 iff length == i, exactly one of them is set so that 0 <= length <= max_size
+	is_end := make([]frontend.Variable, max_size+1)
+	nb_ends := frontend.Variable(0)
+	for i := range is_end {
+		is_end[i] = api.IsZero(api.Sub(length, i))
+		nb_ends = api.Add(nb_ends, is_end[i])
+	}
+	api.AssertIsEqual(nb_ends, 1)
 
-	// Check if we have the same number of hashes and preimages
-	if len(hashes) != len(preimages) {
-		fmt.Println("The number of hashes and pre-images are not equal. Please check your input!")
-		return nil
+	// is_last[b] == 1 iff block b holds the length of the pre-image, i.e. (length + 8) / 64 == b
+	is_last := make([]frontend.Variable, nb_blocks)
+	for b := range is_last {
+		is_last[b] = 0
+	}
+	for i := range is_end {
+		b := (i + 8) / 64
+		is_last[b] = api.Add(is_last[b], is_end[i])
 	}
 
-	// Create a buffer to store logs
-	var buf bytes.Buffer
-	// Overtake the gnark logger with another one that outputs to a buffer and the console
-	multi := zerolog.MultiLevelWriter(zerolog.ConsoleWriter{Out: os.Stdout}, &buf)
-	logger.Set(zerolog.New(multi).With().Timestamp().Logger())
-	// Create a variable to save all the values from the benchmark
-	var outp benchmark.Benchmark_Output
-	// Set the circuit and number of runs
-	outp.Circuit = "sha256"
-	outp.Num_runs = len(hashes)
-	outp.GPU_Acc = GPU_Acc
-	outp.Curve = curve_id.String()
-
-	// Initialize the GPU logging if GPU acceleration is used
-	// Create a channel to signal the GPU sampling function to stop
-	stop := make(chan struct{})
-	// Create a channel to receive the GPU samples
-	GPU_samples := make(chan []gpu.GPU_Sample)
-	if GPU_Acc {
-		// Initilaize NVML
-		gpu.Init_NVML()
-		// Get the GPU device and its name
-		device, name := gpu.Get_device(0)
-		outp.GPU_Name = name
-		// Start the GPU sampling funciton as a goroutine
-		go gpu.GPU_Periodic_Samples(gpu.SAMPLIMG_PERIOD, device, stop, GPU_samples)
-	}
-
-	var SHA256_circuit SHA256Circuit
-	scalarfield := curve_id.ScalarField()
-	// Keep track of the beginning and end time of each step
-	outp.Start_arith = time.Now()
-	// compiles our circuit into a R1CS
-	ccs, _ := frontend.Compile(scalarfield, r1cs.NewBuilder, &SHA256_circuit)
-	outp.Nb_constraints = ccs.GetNbConstraints()
-	outp.End_arith = time.Now()
-
-	// groth16 zkSNARK: Setup
-	fmt.Println("Running setup...")
-	outp.Start_setup = time.Now()
-	pk, vk, _ := groth16.Setup(ccs)
-	outp.End_setup = time.Now()
+	// Bytes of the length in bits, big-endian on 8 bytes
+	length_bits := api.ToBinary(api.Mul(length, 8), 64)
+	length_bytes := make([]frontend.Variable, 8)
+	for t := range length_bytes {
+		length_bytes[7-t] = api.FromBinary(length_bits[8*t : 8*t+8]...)
+	}
 
-	for i := 0; i < len(hashes); i++ {
-		fmt.Printf("Benchmark run %d/%d\n", i+1, len(hashes))
-		//fmt.Println("Hash : ", hex.EncodeToString(hashes[i][:]))
-		//fmt.Println("Pre-image : ", hex.EncodeToString(preimages[i][:]))
-		// Circuit assignement
-		assignment := SHA256Circuit{PreImage: convert_preimage_bytes_to_gnarkU8(preimages[i]), Hash: convert_hash_bytes_to_gnarkU8(hashes[i])}
-		// Witness genration
-		outp.Start_witness_gen = append(outp.Start_witness_gen, time.Now())
-		witness, _ := frontend.NewWitness(&assignment, scalarfield)
-		publicWitness, _ := witness.Public()
-		outp.End_witness_gen = append(outp.End_witness_gen, time.Now())
-		// groth16: Prove & Verify
-		var proof groth16.Proof
-		var err error
-		outp.Start_proof_gen_func = append(outp.Start_proof_gen_func, time.Now())
-		if GPU_Acc {
-			proof, err = groth16.Prove(ccs, pk, witness, backend.WithIcicleAcceleration())
-		} else {
-			proof, err = groth16.Prove(ccs, pk, witness)
+	// Build the padded blocks
+	// byte i = data[i] if i < length, 0x80 if i == length, the length bytes at the end of the last block, 0 otherwise
+	padded := make([]uints.U8, nb_blocks*64)
+	is_data := frontend.Variable(1)
+	for i := range padded {
+		v := frontend.Variable(0)
+		if i <= max_size {
+			// is_data == 1 iff i < length, it is computed as a product to keep the linear expressions short
+			is_data = api.Mul(is_data, api.Sub(1, is_end[i]))
+			v = api.Mul(is_end[i], 0x80)
+			if i < max_size {
+				v = api.Add(v, api.Mul(is_data, data[i].Val))
+			}
+		}
+		if offset := i % 64; offset >= 56 {
+			v = api.Add(v, api.Mul(is_last[i/64], length_bytes[offset-56]))
 		}
-		outp.End_proof_gen_func = append(outp.End_proof_gen_func, time.Now())
+		padded[i] = uints.U8{Val: v}
+	}
 
-		if err != nil {
-			fmt.Println(err)
+	// Compress all the blocks and keep the digest of the last block
+	var running_digest [8]uints.U32
+	var block [64]uints.U8
+	copy(running_digest[:], uints.NewU32Array([]uint32{
+		0x6A09E667, 0xBB67AE85, 0x3C6EF372, 0xA54FF53A, 0x510E527F, 0x9B05688C, 0x1F83D9AB, 0x5BE0CD19,
+	}))
+	res := make([]frontend.Variable, 32)
+	for j := range res {
+		res[j] = 0
+	}
+	for b := 0; b < nb_blocks; b++ {
+		copy(block[:], padded[b*64:(b+1)*64])
+		running_digest = sha2.Permute(uapi, running_digest, block)
+		var digest_bytes []uints.U8
+		for k := range running_digest {
+			digest_bytes = append(digest_bytes, uapi.UnpackMSB(running_digest[k])...)
 		}
-		outp.Start_proof_ver = append(outp.Start_proof_ver, time.Now())
-		err = groth16.Verify(proof, vk, publicWitness)
-		outp.End_proof_ver = append(outp.End_proof_ver, time.Now())
-		if err == nil {
-			fmt.Println("Proof is valid!")
-		} else {
-			fmt.Println("Proof is invalid: ", err)
+		for j := range res {
+			res[j] = api.Add(res[j], api.Mul(is_last[b], digest_bytes[j].Val))
 		}
-		outp.Proof_valid = append(outp.Proof_valid, err == nil)
 	}
 
-	if GPU_Acc {
-		// Signal the GPU sampling function to stop
-		close(stop)
+	ret := make([]uints.U8, 32)
+	for j := range ret {
+		ret[j] = uints.U8{Val: res[j]}
+	}
+	return ret, nil
+}
+
+// New_circuit returns the circuit for pre-images of at most max_size bytes
+func New_circuit(max_size int) *SHA256Circuit {
+	return &SHA256Circuit{PreImage: make([]uints.U8, max_size)}
+}
 
-		// Wait for the periodic function to return the result
-		outp.GPU_samples = <-GPU_samples
+// New_assignment creates the assignment of the circuit for a hash and its pre-image
+// The pre-image is padded with zeros up to max_size bytes
+func New_assignment(hash [32]byte, preimage []byte, max_size int) *SHA256Circuit {
+	data := make([]byte, max_size)
+	copy(data, preimage)
+	return &SHA256Circuit{PreImage: uints.NewU8Array(data), Length: len(preimage), Hash: convert_hash_bytes_to_gnarkU8(hash)}
+}
+
+// Benchmark runs the benchmark of the circuit compiled for pre-images of max_size bytes
+// If max_size is 0, the size of the longest pre-image is used
+func Benchmark(cfg benchmark.Config, max_size int, hashes [][32]byte, preimages [][]byte) error {
+
+	// Check if we have the same number of hashes and preimages
+	if len(hashes) != len(preimages) {
+		fmt.Println("The number of hashes and pre-images are not equal. Please check your input!")
+		return nil
 	}
-	outp.Dbg_log = buf.String()
-	fmt.Println("Compiling benchmark results...")
-	benchmark.Compile(outp)
-	return nil
+	longest := 0
+	for _, preimage := range preimages {
+		if len(preimage) > longest {
+			longest = len(preimage)
+		}
+	}
+	if max_size == 0 {
+		max_size = longest
+	}
+	if longest > max_size {
+		return fmt.Errorf("the longest pre-image has %d bytes, more than the maximum size %d", longest, max_size)
+	}
+
+	// Create the circuit assignments
+	// The invalid assignments use a wrong hash so that the constraints cannot be satisfied
+	assignments := make([]frontend.Circuit, len(hashes))
+	invalid_assignments := make([]frontend.Circuit, len(hashes))
+	for i := 0; i < len(hashes); i++ {
+		assignments[i] = New_assignment(hashes[i], preimages[i], max_size)
+		wrong_hash := hashes[i]
+		wrong_hash[0] ^= 1
+		invalid_assignments[i] = New_assignment(wrong_hash, preimages[i], max_size)
+	}
+
+	cfg.Circuit_params = map[string]int{"SHA-256 max preimage size": max_size, "SHA-256 blocks": Nb_blocks(max_size)}
+	return benchmark.Run(cfg, "sha256", New_circuit(max_size), assignments, invalid_assignments)
 }
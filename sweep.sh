@@ -0,0 +1,31 @@
+#!/bin/bash
+
+#Set the environment variables
+export CGO_LD_FLAGS=-L/root/go/pkg/mod/github.com/ingonyama-zk/icicle@v0.1.0/goicicle
+export LD_LIBRARY_PATH=$LD_LIBRARY_PATH:/root/go/pkg/mod/github.com/ingonyama-zk/icicle@v0.1.0/goicicle/
+
+# Define arrays for each parameter
+# The synthetic circuit has 2^log_size multiplication and addition constraints, add_percent of them being additions
+log_sizes=$(seq 10 24)
+curve_list=("bn254" "bls12_377" "bls12_381" "bw6_761")
+GPU_Acc=(false true)
+add_percent=0
+public=1
+fan_out=1
+n=10
+
+# Iterate over all combinations of parameters
+for log_size in $log_sizes; do
+    size=$((1 << log_size))
+    add=$((size * add_percent / 100))
+    mul=$((size - add))
+    for curve in "${curve_list[@]}"; do
+        for acc in "${GPU_Acc[@]}"; do
+            if [ "$acc" == true ]; then
+                go run -tags=icicle main.go -curve "$curve" -circuit synthetic -GPU_Acc -n "$n" -mul_constraints "$mul" -add_constraints "$add" -public "$public" -fan_out "$fan_out"
+            else
+                go run -tags=icicle main.go -curve "$curve" -circuit synthetic -n "$n" -mul_constraints "$mul" -add_constraints "$add" -public "$public" -fan_out "$fan_out"
+            fi
+        done
+    done
+done
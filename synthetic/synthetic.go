@@ -0,0 +1,305 @@
+package synthetic
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	math_rand "math/rand"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+
+	"gnark_on_icicle/benchmark"
+)
+
+// Seed of the generator of the circuit structure, the same parameters always give the same circuit
+const SEED = 42
+
+// Maximum total number of multiplication and addition constraints
+const MAX_CONSTRAINTS = 1 << 26
+
+// Params holds the runtime parameters of the synthetic circuit
+type Params struct {
+	// Number of multiplication constraints: w = (a_1 + ... + a_f) * (b_1 + ... + b_f)
+	Mul int
+	// Number of addition constraints: w = a_1 + ... + a_f + b_1 + ... + b_f
+	Add int
+	// Number of public inputs, including the public output
+	Public int
+	// Number of wires in each operand of a constraint, each wire is read by about 2*Fan_out constraints
+	Fan_out int
+}
+
+func (p Params) check() error {
+	if p.Mul < 0 || p.Add < 0 || p.Mul+p.Add < 1 {
+		return fmt.Errorf("the circuit needs at least one multiplication or addition constraint")
+	}
+	if p.Mul+p.Add > MAX_CONSTRAINTS {
+		return fmt.Errorf("the circuit has at most %d multiplication and addition constraints", MAX_CONSTRAINTS)
+	}
+	if p.Public < 1 {
+		return fmt.Errorf("the circuit needs at least one public input for the output")
+	}
+	if p.Fan_out < 1 {
+		return fmt.Errorf("the fan-out must be at least 1")
+	}
+	return nil
+}
+
+/* Helper functions */
+
+// walk generates the structure of the circuit and calls visit for each constraint
+// The wires are numbered in order: the secret input, the public inputs (without the output) and then the output
+// of each constraint. Constraint i creates wire nb_inputs + i. The first wire of the left operand is always the
+// previous wire so that every constraint depends on the one before, the other wires are drawn uniformly among the
+// previous wires. The additions are spread evenly between the multiplications.
+func walk(p Params, visit func(is_add bool, left []int, right []int)) {
+	rng := math_rand.New(math_rand.NewSource(SEED))
+	nb_inputs := p.Public
+	total := p.Mul + p.Add
+	left := make([]int, p.Fan_out)
+	right := make([]int, p.Fan_out)
+	for i := 0; i < total; i++ {
+		nb_wires := nb_inputs + i
+		left[0] = nb_wires - 1
+		for j := 1; j < p.Fan_out; j++ {
+			left[j] = rng.Intn(nb_wires)
+		}
+		for j := range right {
+			right[j] = rng.Intn(nb_wires)
+		}
+		is_add := (i+1)*p.Add/total > i*p.Add/total
+		visit(is_add, left, right)
+	}
+}
+
+// Evaluate computes the output of the circuit natively for the given secret input and public inputs
+func Evaluate(p Params, modulus *big.Int, secret *big.Int, inputs []*big.Int) *big.Int {
+	wires := make([]*big.Int, 0, p.Public+p.Mul+p.Add)
+	wires = append(wires, secret)
+	wires = append(wires, inputs...)
+	sum := func(indices []int) *big.Int {
+		s := new(big.Int)
+		for _, j := range indices {
+			s.Add(s, wires[j])
+		}
+		return s
+	}
+	walk(p, func(is_add bool, left []int, right []int) {
+		var w *big.Int
+		if is_add {
+			w = new(big.Int).Add(sum(left), sum(right))
+		} else {
+			w = new(big.Int).Mul(sum(left), sum(right))
+		}
+		wires = append(wires, w.Mod(w, modulus))
+	})
+	return wires[len(wires)-1]
+}
+
+// Gen_rand_inputs generates n random secret inputs and public inputs and computes the outputs
+func Gen_rand_inputs(n int, p Params, modulus *big.Int) ([]*big.Int, [][]*big.Int, []*big.Int, error) {
+	if err := p.check(); err != nil {
+		return nil, nil, nil, err
+	}
+	secrets := make([]*big.Int, n)
+	inputs := make([][]*big.Int, n)
+	outputs := make([]*big.Int, n)
+
+	for i := 0; i < n; i++ {
+		var err error
+		secrets[i], err = rand.Int(rand.Reader, modulus)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		inputs[i] = make([]*big.Int, p.Public-1)
+		for j := range inputs[i] {
+			inputs[i][j], err = rand.Int(rand.Reader, modulus)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		outputs[i] = Evaluate(p, modulus, secrets[i], inputs[i])
+	}
+
+	return secrets, inputs, outputs, nil
+}
+
+// Function to read file and extract the inputs
+// Each line contains the secret input, the public inputs and the output, all in decimal. All the lines must have
+// the same number of public inputs, which gives the number of public inputs of the circuit.
+// The outputs depend on the parameters of the circuit and on the curve.
+func Parse_file(file_path string) ([]*big.Int, [][]*big.Int, []*big.Int, error) {
+	// Open the file
+	file, err := os.Open(file_path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer file.Close()
+
+	var secrets []*big.Int
+	var inputs [][]*big.Int
+	var outputs []*big.Int
+
+	scanner := bufio.NewScanner(file)
+	// The lines get long with many public inputs
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line_num := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		line_num++
+		parts := strings.Split(line, " ")
+		if len(parts) < 2 {
+			return nil, nil, nil, fmt.Errorf("invalid line format: %s", line)
+		}
+		values := make([]*big.Int, len(parts))
+		for j := range parts {
+			var succ bool
+			values[j], succ = new(big.Int).SetString(parts[j], 10)
+			if !succ {
+				return nil, nil, nil, fmt.Errorf("error decoding value %d at line %d: Failed to convert string to big.Int", j, line_num)
+			}
+		}
+		if len(inputs) > 0 && len(values)-2 != len(inputs[0]) {
+			return nil, nil, nil, fmt.Errorf("line %d has %d public inputs instead of %d", line_num, len(values)-2, len(inputs[0]))
+		}
+		secrets = append(secrets, values[0])
+		inputs = append(inputs, values[1:len(values)-1])
+		outputs = append(outputs, values[len(values)-1])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return secrets, inputs, outputs, nil
+}
+
+// sum_hint returns the sum of its inputs, it gives the value of the wire created by an addition constraint
+func sum_hint(_ *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	outputs[0].SetInt64(0)
+	for _, x := range inputs {
+		outputs[0].Add(outputs[0], x)
+	}
+	return nil
+}
+
+func init() {
+	solver.RegisterHint(sum_hint)
+}
+
+// SyntheticCircuit defines a circuit with a given number of multiplication and addition constraints
+// The secret input and the public inputs are the first wires, the output of the last constraint is public
+type SyntheticCircuit struct {
+	Secret frontend.Variable
+	Inputs []frontend.Variable `gnark:",public"`
+	Output frontend.Variable   `gnark:",public"`
+
+	Params Params `gnark:"-"`
+}
+
+// Define declares the circuit's constraints
+// Output == w_last where each wire is computed by a multiplication or an addition constraint of previous wires
+func (circuit *SyntheticCircuit) Define(api frontend.API) error {
+	if err := circuit.Params.check(); err != nil {
+		return err
+	}
+	wires := make([]frontend.Variable, 0, circuit.Params.Public+circuit.Params.Mul+circuit.Params.Add)
+	wires = append(wires, circuit.Secret)
+	wires = append(wires, circuit.Inputs...)
+	sum := func(indices []int) frontend.Variable {
+		terms := make([]frontend.Variable, len(indices))
+		for j, k := range indices {
+			terms[j] = wires[k]
+		}
+		if len(terms) == 1 {
+			return terms[0]
+		}
+		return api.Add(terms[0], terms[1], terms[2:]...)
+	}
+	var err error
+	walk(circuit.Params, func(is_add bool, left []int, right []int) {
+		if err != nil {
+			return
+		}
+		if is_add {
+			// Additions are free in R1CS, so the sum is given by a hint and checked with one constraint
+			// (a_1 + ... + b_f) * 1 == w
+			operands := []frontend.Variable{sum(left), sum(right)}
+			var res []frontend.Variable
+			res, err = api.Compiler().NewHint(sum_hint, 1, operands...)
+			if err != nil {
+				return
+			}
+			api.AssertIsEqual(res[0], api.Add(operands[0], operands[1]))
+			wires = append(wires, res[0])
+		} else {
+			wires = append(wires, api.Mul(sum(left), sum(right)))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(circuit.Output, wires[len(wires)-1])
+	return nil
+}
+
+// New_circuit returns the circuit with the shape given by the parameters
+func New_circuit(p Params) (*SyntheticCircuit, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	return &SyntheticCircuit{Inputs: make([]frontend.Variable, p.Public-1), Params: p}, nil
+}
+
+func Benchmark(cfg benchmark.Config, p Params, secrets []*big.Int, inputs [][]*big.Int, outputs []*big.Int) error {
+	circuit, err := New_circuit(p)
+	if err != nil {
+		return err
+	}
+	if len(secrets) != len(inputs) || len(secrets) != len(outputs) {
+		fmt.Println("The number of secrets, inputs and outputs are not equal. Please check your input!")
+		return nil
+	}
+
+	if len(secrets) == 0 {
+		fmt.Println("No inputs were given. Please check your input!")
+		return nil
+	}
+
+	// Create the circuit assignments
+	// The invalid assignments use a wrong output so that the last constraint cannot be satisfied
+	assignments := make([]frontend.Circuit, len(secrets))
+	invalid_assignments := make([]frontend.Circuit, len(secrets))
+	modulus := cfg.Curve_id.ScalarField()
+	for i := range secrets {
+		if len(inputs[i]) != p.Public-1 {
+			return fmt.Errorf("expected %d public inputs, got %d", p.Public-1, len(inputs[i]))
+		}
+		// The outputs of a file depend on the parameters of the circuit and on the curve, they are checked natively
+		// so that inputs generated with other parameters fail before the compilation
+		if Evaluate(p, modulus, secrets[i], inputs[i]).Cmp(new(big.Int).Mod(outputs[i], modulus)) != 0 {
+			return fmt.Errorf("the output of input %d does not match the circuit, the inputs were generated for other "+
+				"parameters (-mul_constraints, -add_constraints, -fan_out) or for another curve", i)
+		}
+		a := SyntheticCircuit{Secret: secrets[i], Inputs: make([]frontend.Variable, p.Public-1), Output: outputs[i]}
+		for j := range inputs[i] {
+			a.Inputs[j] = inputs[i][j]
+		}
+		wrong := a
+		wrong.Output = new(big.Int).Add(outputs[i], big.NewInt(1))
+		assignments[i] = &a
+		invalid_assignments[i] = &wrong
+	}
+
+	cfg.Circuit_params = map[string]int{
+		"Multiplication constraints": p.Mul,
+		"Addition constraints":       p.Add,
+		"Public inputs":              p.Public,
+		"Fan-out":                    p.Fan_out,
+	}
+	return benchmark.Run(cfg, "synthetic", circuit, assignments, invalid_assignments)
+}